@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var archiveTemplate = template.Must(template.New("archive").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Archived from <a href="{{.URL}}">{{.URL}}</a> on {{.ArchivedAt.Format "2006-01-02 15:04 MST"}}</p>
+<hr>
+{{.Body}}
+</body>
+</html>
+`))
+
+// archiver saves posts into a content addressed store: every file is
+// named after the sha256 of its content, so images shared by posts are
+// only stored once.
+type archiver struct {
+	dir string
+}
+
+func handlerArchive(s *state, cmd command, user database.User) error {
+	var feedURLs stringList
+	fs := newFlagSet(cmd.name)
+	fs.Var(&feedURLs, "feed", "url of a feed to archive, can be repeated")
+	starred := fs.Bool("starred", false, "archive the posts starred by the user")
+	_, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(feedURLs) == 0 && !*starred {
+		return errors.New("please select the posts to archive with --starred or --feed <url>")
+	}
+
+	var posts []database.Post
+	if *starred {
+		starredPosts, err := s.db.GetStarredPostsToArchive(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't get starred posts: %w", err)
+		}
+		posts = append(posts, starredPosts...)
+	}
+
+	if len(feedURLs) > 0 {
+		var feedIDs []uuid.UUID
+		for _, feedURL := range feedURLs {
+			feed, err := resolveFeed(s, feedURL)
+			if err != nil {
+				return err
+			}
+			feedIDs = append(feedIDs, feed.ID)
+		}
+
+		feedPosts, err := s.db.GetPostsToArchive(context.Background(), feedIDs)
+		if err != nil {
+			return fmt.Errorf("couldn't get posts to archive: %w", err)
+		}
+		for _, post := range feedPosts {
+			// starred posts of the feeds are already in the list
+			if !slices.ContainsFunc(posts, func(p database.Post) bool { return p.ID == post.ID }) {
+				posts = append(posts, post)
+			}
+		}
+	}
+
+	dir, err := s.cfg.GetArchiveDir()
+	if err != nil {
+		return err
+	}
+	a := &archiver{dir: dir}
+
+	archived := 0
+	for _, post := range posts {
+		archivePath, err := a.archivePost(context.Background(), post)
+		if err != nil {
+			fmt.Printf("couldn't archive %s: %v\n", post.Url, err)
+			continue
+		}
+		err = s.db.SetPostArchivePath(context.Background(), database.SetPostArchivePathParams{
+			ArchivePath: sql.NullString{
+				String: archivePath,
+				Valid:  true,
+			},
+			UpdatedAt: time.Now().UTC(),
+			ID:        post.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't record archive of %s: %w", post.Url, err)
+		}
+		fmt.Printf("archived %s to %s\n", post.Url, archivePath)
+		archived++
+	}
+
+	fmt.Printf("archived %d of %d posts\n", archived, len(posts))
+	return nil
+}
+
+// archivePost saves the article of post as a standalone HTML page with its
+// images next to it and returns the path of the page.
+func (a *archiver) archivePost(ctx context.Context, post database.Post) (string, error) {
+	page, err := fetchURL(ctx, post.Url)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := extractArticle(page, post.Url)
+	if errors.Is(err, errNoArticle) {
+		body = sanitizeHTML(string(page))
+	} else if err != nil {
+		return "", err
+	}
+
+	body, err = a.archiveImages(ctx, body, post.Url)
+	if err != nil {
+		return "", err
+	}
+
+	title := post.Title.String
+	if title == "" {
+		title = post.Url
+	}
+
+	var buf bytes.Buffer
+	err = archiveTemplate.Execute(&buf, struct {
+		Title      string
+		URL        string
+		ArchivedAt time.Time
+		Body       template.HTML
+	}{
+		Title:      title,
+		URL:        post.Url,
+		ArchivedAt: time.Now().UTC(),
+		// body has been sanitized
+		Body: template.HTML(body),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return a.store(buf.Bytes(), ".html")
+}
+
+// archiveImages stores the images of the HTML fragment body and points
+// them to the stored copies. Images that can't be fetched keep their url.
+func (a *archiver) archiveImages(ctx context.Context, body string, pageURL string) (string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	nodes, err := parseHTMLFragment(body)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		walkNodes(node, func(n *html.Node) {
+			if n.DataAtom != atom.Img {
+				return
+			}
+			for i, attr := range n.Attr {
+				if attr.Key != "src" {
+					continue
+				}
+				ref, err := url.Parse(attr.Val)
+				if err != nil {
+					continue
+				}
+				src := base.ResolveReference(ref).String()
+				image, err := fetchURL(ctx, src)
+				if err != nil {
+					log.Printf("couldn't archive image %s: %v", src, err)
+					continue
+				}
+				stored, err := a.store(image, imageExtension(image, src))
+				if err != nil {
+					log.Printf("couldn't archive image %s: %v", src, err)
+					continue
+				}
+				// pages and images share a directory
+				n.Attr[i].Val = filepath.Base(stored)
+			}
+		})
+		err = html.Render(&buf, node)
+		if err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// store writes data to the archive unless it is already there and returns
+// its path.
+func (a *archiver) store(data []byte, ext string) (string, error) {
+	sum := sha256.Sum256(data)
+	storePath := filepath.Join(a.dir, hex.EncodeToString(sum[:])+ext)
+
+	if _, err := os.Stat(storePath); err == nil {
+		return storePath, nil
+	}
+
+	err := os.MkdirAll(a.dir, 0o755)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(a.dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return "", err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return "", err
+	}
+	err = os.Chmod(tmp.Name(), 0o644)
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(tmp.Name(), storePath)
+	if err != nil {
+		return "", err
+	}
+	return storePath, nil
+}
+
+func imageExtension(data []byte, src string) string {
+	contentType := http.DetectContentType(data)
+	if strings.HasPrefix(contentType, "image/") {
+		switch contentType {
+		case "image/jpeg":
+			return ".jpg"
+		case "image/png":
+			return ".png"
+		case "image/gif":
+			return ".gif"
+		case "image/webp":
+			return ".webp"
+		}
+		if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+			return exts[0]
+		}
+	}
+	if parsed, err := url.Parse(src); err == nil {
+		if ext := path.Ext(parsed.Path); ext != "" && len(ext) <= 5 {
+			return strings.ToLower(ext)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a 1x1 png
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89\x00\x00\x00\rIDATx\x9cc\xf8\x0f\x00\x00\x01\x01\x00\x05\x18\xd8N\x00\x00\x00\x00IEND\xaeB`\x82")
+
+func newArchiveServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	page, err := os.ReadFile("testdata/articles/blog.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fixture points its images to blog.example.com
+	page = []byte(strings.Replace(string(page), `<base href="https://blog.example.com/posts/">`, "", 1))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/posts/postgres.html":
+			w.Write(page)
+		case "/posts/images/graph.png":
+			w.Write(testPNG)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	useHostLimiter(t, 0)
+	return server
+}
+
+func TestArchivePost(t *testing.T) {
+	server := newArchiveServer(t)
+	_, q := newTestState(t)
+	alice := seedUser(q, "alice")
+	feed := seedFeed(q, alice, "blog", "https://example.com/rss")
+	post := seedPost(q, feed, "Running Postgres", server.URL+"/posts/postgres.html", time.Now())
+
+	a := &archiver{dir: t.TempDir()}
+	archivePath, err := a.archivePost(context.Background(), post)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archived, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(archived)
+	if filepath.Base(archivePath) != hex.EncodeToString(sum[:])+".html" {
+		t.Errorf("expected page to be named after its hash, got %s", archivePath)
+	}
+
+	imageSum := sha256.Sum256(testPNG)
+	imageName := hex.EncodeToString(imageSum[:]) + ".png"
+	image, err := os.ReadFile(filepath.Join(a.dir, imageName))
+	if err != nil {
+		t.Fatalf("expected image to be archived: %v", err)
+	}
+	if string(image) != string(testPNG) {
+		t.Error("archived image differs")
+	}
+
+	for _, want := range []string{
+		"<title>Running Postgres</title>",
+		"Archived from <a href=\"" + server.URL + "/posts/postgres.html\">",
+		"test your backups",
+		`<img src="` + imageName + `"`,
+	} {
+		if !strings.Contains(string(archived), want) {
+			t.Errorf("expected archive to contain %q, got:\n%s", want, archived)
+		}
+	}
+	if strings.Contains(string(archived), "Great post") {
+		t.Error("expected comments not to be archived")
+	}
+
+	// archiving again stores the same files
+	again, err := a.archivePost(context.Background(), post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 3 {
+		t.Errorf("expected no duplicate files, got %d entries (%s and %s)", len(entries), archivePath, again)
+	}
+}
+
+func TestHandlerArchive(t *testing.T) {
+	server := newArchiveServer(t)
+
+	runHandlerTests(t, middlewareLoggedIn(handlerArchive), []handlerTest{
+		{
+			name:        "no feeds selected",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			wantErr:     "please select the posts to archive",
+		},
+		{
+			name:        "unknown feed",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:        []string{"--feed", "https://example.com/rss"},
+			wantErr:     "couldn't find feed https://example.com/rss",
+		},
+		{
+			name:        "archives posts of the feed",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+				seedPost(q, blog, "postgres", server.URL+"/posts/postgres.html", time.Now())
+				seedPost(q, blog, "missing", server.URL+"/posts/missing.html", time.Now())
+				seedPost(q, news, "other", server.URL+"/posts/other.html", time.Now())
+			},
+			args:    []string{"--feed", "https://example.com/rss"},
+			wantOut: []string{"archived " + server.URL + "/posts/postgres.html to ", "couldn't archive " + server.URL + "/posts/missing.html", "archived 1 of 2 posts"},
+			notOut:  []string{"other.html"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if !q.posts[0].ArchivePath.Valid {
+					t.Error("expected archive path to be recorded")
+				}
+				if !strings.HasPrefix(q.posts[0].ArchivePath.String, filepath.Join(os.Getenv("HOME"), ".gator", "archive")) {
+					t.Errorf("expected archive in the home directory, got %s", q.posts[0].ArchivePath.String)
+				}
+				if q.posts[1].ArchivePath.Valid || q.posts[2].ArchivePath.Valid {
+					t.Error("expected only the fetched post to be archived")
+				}
+			},
+		},
+		{
+			name:        "archives starred posts",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				bob := seedUser(q, "bob")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				starred := seedPost(q, blog, "postgres", server.URL+"/posts/postgres.html", time.Now())
+				other := seedPost(q, blog, "other", server.URL+"/posts/other.html", time.Now())
+				seedStar(q, alice, starred)
+				seedStar(q, bob, other)
+			},
+			args:    []string{"--starred"},
+			wantOut: []string{"archived " + server.URL + "/posts/postgres.html to ", "archived 1 of 1 posts"},
+			notOut:  []string{"other.html"},
+		},
+		{
+			name:        "starred posts and feeds are archived once",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				starred := seedPost(q, blog, "postgres", server.URL+"/posts/postgres.html", time.Now())
+				seedStar(q, alice, starred)
+			},
+			args:    []string{"--starred", "--feed", "https://example.com/rss"},
+			wantOut: []string{"archived 1 of 1 posts"},
+		},
+		{
+			name:        "feed url in another form",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				seedPost(q, blog, "postgres", server.URL+"/posts/postgres.html", time.Now())
+			},
+			args:    []string{"--feed", "HTTPS://Example.com/rss?utm_source=x"},
+			wantOut: []string{"archived 1 of 1 posts"},
+		},
+	})
+}
+
+func TestHandlerBrowseArchived(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerBrowse), []handlerTest{
+		{
+			name:        "only archived posts",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				seedFollow(q, alice, blog)
+				seedPost(q, blog, "archived", "https://example.com/1", time.Now().Add(-time.Hour))
+				seedPost(q, blog, "fresh", "https://example.com/2", time.Now())
+				q.posts[0].ArchivePath = sql.NullString{String: "/archive/abc.html", Valid: true}
+			},
+			args:    []string{"5", "--archived"},
+			wantOut: []string{"title: archived", "archived copy: file:///archive/abc.html"},
+			notOut:  []string{"title: fresh"},
+		},
+	})
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"context"
+	"os"
 	"time"
 	"strconv"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/arglp/gator/internal/database"
@@ -39,10 +43,13 @@ func handlerLogin(s *state, cmd command) error {
 
 	name := cmd.args[0]
 	
-	_, err := s.db.GetUser(context.Background(), name)
+	user, err := s.db.GetUser(context.Background(), name)
 	if err != nil {
 		return fmt.Errorf("user %s doesn't exist", name)
 	}
+	if user.ID == systemUserID {
+		return fmt.Errorf("%s is the system user and can't log in", name)
+	}
 
 	err = s.cfg.SetUser(name)
 	if err != nil {
@@ -59,6 +66,9 @@ func handlerRegister(s *state, cmd command) error {
 	}
 
 	name := cmd.args[0]
+	if name == systemUserName {
+		return fmt.Errorf("username %s is reserved", name)
+	}
 	_, err := s.db.GetUser(context.Background(), name)
 	if err == nil {
 		return fmt.Errorf("username %s already exists", name)
@@ -86,7 +96,12 @@ func handlerRegister(s *state, cmd command) error {
 }
 
 func handlerReset(s *state, cmd command) error {
-	err := s.db.DeleteUsers(context.Background())
+	// feeds don't go away with their owners
+	err := s.db.DeleteFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("couldn't delete feeds: %w", err)
+	}
+	err = s.db.DeleteUsers(context.Background())
 	if err != nil {
 		return fmt.Errorf("couldn't delete users: %w", err)
 	}
@@ -98,6 +113,24 @@ func handlerUsers(s *state, cmd command) error {
 	if err != nil {
 		return fmt.Errorf("couldn't get users: %w", err)
 	}
+	// the system user only owns orphaned feeds, it isn't a user to log in as
+	users = slices.DeleteFunc(users, func(user database.User) bool {
+		return user.ID == systemUserID
+	})
+
+	if s.output != outputText {
+		var records []userRecord
+		for _, user := range users {
+			records = append(records, userRecord{
+				ID: user.ID,
+				CreatedAt: user.CreatedAt,
+				UpdatedAt: user.UpdatedAt,
+				Name: user.Name,
+				Current: user.Name == s.cfg.CurrentUserName,
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
 
 	if len(users) == 0 {
 		fmt.Println("no users registered")
@@ -142,23 +175,47 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		return errors.New("please provide name and url")
 	}
 
-	userId := user.ID
 	name := cmd.args[0]
 	url := cmd.args[1]
 
+	feed, _, err := addFeed(s, user, name, url)
+	if err != nil {
+		return err
+	}
+	fmt.Println("added feed:")
+	fmt.Println(feed.ID)
+	fmt.Println(feed.CreatedAt)
+	fmt.Println(feed.UpdatedAt)
+	fmt.Println(feed.Name)
+	fmt.Println(feed.Url)
+	fmt.Println(feed.UserID)
+	return nil
+}
+
+// addFeed creates a feed owned by user and follows it. The url is stored
+// in its canonical form, urls of existing feeds are refused.
+func addFeed(s *state, user database.User, name, url string) (database.Feed, database.CreateFeedFollowRow, error) {
+	url, err := canonicalURL(url)
+	if err != nil {
+		return database.Feed{}, database.CreateFeedFollowRow{}, err
+	}
+	if existing, err := getFeedByURL(s, url); err == nil {
+		return database.Feed{}, database.CreateFeedFollowRow{}, fmt.Errorf("the feed %s already has the url %s, please follow it instead", existing.Name, existing.Url)
+	}
+
 	feed, err := s.db.CreateFeed(context.Background(), database.CreateFeedParams{
 		ID: uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 		Name: name,
 		Url: url,
-		UserID: userId,
+		UserID: user.ID,
 	})
 	if err != nil {
-		return err
+		return database.Feed{}, database.CreateFeedFollowRow{}, err
 	}
 
-	_, err = s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	follow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
 		ID: uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
@@ -166,25 +223,130 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		FeedID: feed.ID,
 	})
 	if err != nil {
-		return err
+		return database.Feed{}, database.CreateFeedFollowRow{}, err
 	}
-	fmt.Println("added feed:")
-	fmt.Println(feed.ID)
-	fmt.Println(feed.CreatedAt)
-	fmt.Println(feed.UpdatedAt)
-	fmt.Println(feed.Name)
-	fmt.Println(feed.Url)
-	fmt.Println(feed.UserID)
-	return nil
+	return feed, follow, nil
+}
+
+// popularFeedsLimit is the number of feeds feeds --popular shows unless
+// --limit is given.
+const popularFeedsLimit = 10
+
+// feedDescriptionLen is the number of characters of a channel description
+// feeds and following show.
+const feedDescriptionLen = 100
+
+// shortDescription puts a channel description on one line of at most
+// feedDescriptionLen characters.
+func shortDescription(description string) string {
+	return excerpt(strings.Join(strings.Fields(description), " "), feedDescriptionLen)
 }
 
 func handlerFeeds(s *state, cmd command) error {
-	feeds, err := s.db.GetFeeds(context.Background())
+	fs := newFlagSet(cmd.name)
+	search := fs.String("search", "", "only show feeds whose name, url or description contain the text")
+	sortBy := fs.String("sort", "", "sort the feeds by followers, recent or name")
+	popular := fs.Bool("popular", false, "show the feeds most followed by other users")
+	limit := fs.Int("limit", 0, "show at most this many feeds")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 || *limit < 0 {
+		return errors.New("usage: feeds [--search <text>] [--sort followers|recent|name] [--popular] [--limit N]")
+	}
+	switch *sortBy {
+	case "", "followers", "recent", "name":
+	default:
+		return fmt.Errorf("unknown sort %s, please use followers, recent or name", *sortBy)
+	}
+
+	params := database.SearchFeedsParams{Sort: *sortBy}
+	if *search != "" {
+		params.Search = sql.NullString{String: "%" + escapeLike(*search) + "%", Valid: true}
+	}
+	if *popular {
+		// feeds the active user already follows aren't news to them
+		user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUserName)
+		if err != nil {
+			return errors.New("feeds --popular needs an active user, please log in")
+		}
+		params.ExcludeUserID = uuid.NullUUID{UUID: user.ID, Valid: true}
+		params.MinFollowers = 1
+		if params.Sort == "" {
+			params.Sort = "followers"
+		}
+		if *limit == 0 {
+			*limit = popularFeedsLimit
+		}
+	}
+	if *limit > 0 {
+		params.Limit = sql.NullInt32{Int32: int32(*limit), Valid: true}
+	}
+	feeds, err := s.db.SearchFeeds(context.Background(), params)
 	if err != nil {
 		return errors.New("couldn't get feeds")
 	}
+
+	if s.output != outputText {
+		var records []feedRecord
+		for _, feed := range feeds {
+			records = append(records, feedRecord{
+				ID: feed.ID,
+				CreatedAt: feed.CreatedAt,
+				UpdatedAt: feed.UpdatedAt,
+				Name: feed.Name,
+				URL: feed.Url,
+				UserID: feed.UserID,
+				UserName: feed.UserName,
+				Followers: feed.Followers,
+				LastFetchedAt: nullTime(feed.LastFetchedAt.Time, feed.LastFetchedAt.Valid),
+				Title: nullString(feed.Title.String, feed.Title.Valid),
+				SiteURL: nullString(feed.SiteUrl.String, feed.SiteUrl.Valid),
+				Description: nullString(feed.Description.String, feed.Description.Valid),
+				Language: nullString(feed.Language.String, feed.Language.Valid),
+				ImageURL: nullString(feed.ImageUrl.String, feed.ImageUrl.Valid),
+				Generator: nullString(feed.Generator.String, feed.Generator.Valid),
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+
+	if len(feeds) == 0 {
+		switch {
+		case *popular:
+			fmt.Println("no feeds followed by other users that you don't follow yet")
+		case *search != "":
+			fmt.Printf("no feeds match %s\n", *search)
+		default:
+			fmt.Println("no feeds")
+		}
+		return nil
+	}
 	for _, feed := range feeds{
-		fmt.Printf("name: %s, url: %s, user: %s\n", feed.Name, feed.Url, feed.UserName)
+		fmt.Printf("name: %s, url: %s, user: %s, followers: %d\n", feed.Name, feed.Url, feed.UserName, feed.Followers)
+		// the channel is only known once agg has fetched the feed
+		if feed.Title.Valid || feed.Description.Valid {
+			// keep long or multi-line descriptions on the title line
+			title := strings.Join(strings.Fields(feed.Title.String), " ")
+			fmt.Printf("  %s\n", strings.Join(nonEmpty(title, shortDescription(feed.Description.String)), " - "))
+		}
+		var details []string
+		if feed.SiteUrl.Valid {
+			details = append(details, "site: "+feed.SiteUrl.String)
+		}
+		if feed.Language.Valid {
+			details = append(details, "language: "+feed.Language.String)
+		}
+		if feed.ImageUrl.Valid {
+			details = append(details, "image: "+feed.ImageUrl.String)
+		}
+		if feed.Generator.Valid {
+			details = append(details, "generator: "+feed.Generator.String)
+		}
+		if len(details) > 0 {
+			fmt.Printf("  %s\n", strings.Join(details, ", "))
+		}
 	}
 	return nil
 }
@@ -193,26 +355,43 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 	if len(cmd.args) < 1 {
 		return errors.New("required more arguments")
 	}
-	url := cmd.args[0]
-	feed, err := s.db.GetFeed(context.Background(), url)
-	if err != nil {
-		return errors.New ("couldn't find feed")
+	// resolve all feeds first, so a typo doesn't leave half of them followed
+	var feeds []database.Feed
+	for _, ref := range cmd.args {
+		feed, err := resolveFeed(s, ref)
+		if err != nil {
+			return err
+		}
+		feeds = append(feeds, feed)
 	}
 
-	follow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
-		ID: uuid.New(),
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		UserID: user.ID,
-		FeedID: feed.ID,
-	})
+	// a feed that can't be followed doesn't keep the others from being followed
+	var failed []string
+	for _, feed := range feeds {
+		follow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+			ID: uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			UserID: user.ID,
+			FeedID: feed.ID,
+		})
 
-	if err != nil {
-		return errors.New("couldn't follow")
-	}
+		if err != nil {
+			if _, followErr := s.db.GetFeedFollow(context.Background(), database.GetFeedFollowParams{UserID: user.ID, FeedID: feed.ID}); followErr == nil {
+				fmt.Printf("already following %s\n", feed.Name)
+			} else {
+				fmt.Printf("couldn't follow %s: %v\n", feed.Name, err)
+			}
+			failed = append(failed, feed.Name)
+			continue
+		}
 
-	fmt.Println("following new feed")
-	fmt.Printf("user: %s, feed: %s\n", follow.UserName, follow.FeedName)
+		fmt.Println("following new feed")
+		fmt.Printf("user: %s, feed: %s\n", follow.UserName, follow.FeedName)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("couldn't follow %s", strings.Join(failed, ", "))
+	}
 	return nil
 }
 
@@ -222,9 +401,74 @@ func handlerFollowing( s* state, cmd command, user database.User) error {
 	if err != nil {
 		return errors.New("couldn't find followed feeds")
 	}
+	tags, err := s.db.GetTagsForUser(context.Background(), user.ID)
+	if err != nil {
+		return errors.New("couldn't find tags")
+	}
+	tagNames := func(followID uuid.UUID) []string {
+		names := []string{}
+		for _, tag := range tags {
+			if tag.FeedFollowID == followID {
+				names = append(names, tag.Name)
+			}
+		}
+		return names
+	}
+
+	if s.output != outputText {
+		var records []followRecord
+		for _, follow := range follows {
+			records = append(records, followRecord{
+				ID: follow.ID,
+				CreatedAt: follow.CreatedAt,
+				UpdatedAt: follow.UpdatedAt,
+				UserID: follow.UserID,
+				UserName: follow.UserName,
+				FeedID: follow.FeedID,
+				FeedName: follow.FeedName,
+				FeedURL: follow.FeedUrl,
+				FeedTitle: nullString(follow.FeedTitle.String, follow.FeedTitle.Valid),
+				FeedSiteURL: nullString(follow.FeedSiteUrl.String, follow.FeedSiteUrl.Valid),
+				FeedDescription: nullString(follow.FeedDescription.String, follow.FeedDescription.Valid),
+				FeedLanguage: nullString(follow.FeedLanguage.String, follow.FeedLanguage.Valid),
+				FeedImageURL: nullString(follow.FeedImageUrl.String, follow.FeedImageUrl.Valid),
+				FeedGenerator: nullString(follow.FeedGenerator.String, follow.FeedGenerator.Valid),
+				Tags: tagNames(follow.ID),
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
 	fmt.Printf("user: %s is following these feeds:\n", user.Name)
 	for _, follow := range follows {
-		fmt.Println(follow.FeedName)
+		names := tagNames(follow.ID)
+		line := follow.FeedName
+		if len(names) > 0 {
+			line += " (" + strings.Join(names, ", ") + ")"
+		}
+		channel := nonEmpty(follow.FeedTitle.String, follow.FeedSiteUrl.String)
+		if follow.FeedTitle.String == follow.FeedName {
+			channel = nonEmpty(follow.FeedSiteUrl.String)
+		}
+		if len(channel) > 0 {
+			line += " - " + strings.Join(channel, ", ")
+		}
+		fmt.Println(line)
+		if follow.FeedDescription.Valid {
+			fmt.Printf("  %s\n", shortDescription(follow.FeedDescription.String))
+		}
+		var details []string
+		if follow.FeedLanguage.Valid {
+			details = append(details, "language: "+follow.FeedLanguage.String)
+		}
+		if follow.FeedImageUrl.Valid {
+			details = append(details, "image: "+follow.FeedImageUrl.String)
+		}
+		if follow.FeedGenerator.Valid {
+			details = append(details, "generator: "+follow.FeedGenerator.String)
+		}
+		if len(details) > 0 {
+			fmt.Printf("  %s\n", strings.Join(details, ", "))
+		}
 	}
 	return nil
 }
@@ -233,45 +477,489 @@ func handlerUnfollow(s* state, cmd command, user database.User) error {
 	if len(cmd.args) < 1 {
 		return errors.New("required more arguments")
 	}
-	url := cmd.args[0]
-
-	feed, err := s.db.GetFeed(context.Background(), url)
-	if err != nil {
-		return err
+	var feeds []database.Feed
+	for _, ref := range cmd.args {
+		feed, _, err := resolveFollow(s, user, ref)
+		if err != nil {
+			return err
+		}
+		feeds = append(feeds, feed)
 	}
-	err = s.db.DeleteFeedFollow(context.Background(),database.DeleteFeedFollowParams{
-		UserID: user.ID,
-		FeedID: feed.ID,
-	})
-	if err != nil {
-		return err
+
+	for _, feed := range feeds {
+		err := s.db.DeleteFeedFollow(context.Background(),database.DeleteFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("unfollowed %s\n", feed.Name)
 	}
 	return nil
 }
 
 func handlerBrowse(s* state, cmd command, user database.User) error {
 	
+	fs := newFlagSet(cmd.name)
+	archived := fs.Bool("archived", false, "only show archived posts")
+	all := fs.Bool("all", false, "include posts that have been read")
+	var before, after cursorFlag
+	fs.Var(&before, "before", "only show posts older than the cursor")
+	fs.Var(&after, "after", "only show posts newer than the cursor")
+	page := fs.Int("page", 1, "page of posts to show")
+	feedRef := fs.String("feed", "", "only show posts of this feed (url or name)")
+	since := fs.String("since", "", "only show posts published since this date")
+	until := fs.String("until", "", "only show posts published before this date")
+	match := fs.String("match", "", "only show posts containing this text")
+	tag := fs.String("tag", "", "only show posts of the feeds with this tag")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if *page < 1 {
+		return errors.New("page has to be at least 1")
+	}
+
 	var limit int32 = 2
-	if len(cmd.args) > 0 {
-		limit64, err := strconv.ParseInt(cmd.args[0], 10, 32)
+	if len(args) > 0 {
+		limit64, err := strconv.ParseInt(args[0], 10, 32)
 		if err == nil {
 			limit = int32(limit64)
 		}
 	}
 
-	posts, err := s.db.GetPostForUser(context.Background(), database.GetPostForUserParams{
+	params := database.GetPostForUserParams{
 		UserID: user.ID,
+		Archived: *archived,
+		IncludeRead: *all,
+		Offset: int32(*page-1) * limit,
 		Limit: limit,
-	})
+	}
+	if *feedRef != "" {
+		feed, err := resolveFeed(s, *feedRef)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	now := time.Now().UTC()
+	if *since != "" {
+		t, err := parseTimeFlag(*since, now)
+		if err != nil {
+			return err
+		}
+		params.Since = sql.NullTime{Time: t, Valid: true}
+	}
+	if *until != "" {
+		t, err := parseTimeFlag(*until, now)
+		if err != nil {
+			return err
+		}
+		params.Until = sql.NullTime{Time: t, Valid: true}
+	}
+	if *tag != "" {
+		name, err := normalizeTag(*tag)
+		if err != nil {
+			return err
+		}
+		params.Tag = sql.NullString{String: name, Valid: true}
+	}
+	if *match != "" {
+		params.Match = sql.NullString{String: "%" + escapeLike(*match) + "%", Valid: true}
+	}
+	if before.cursor != nil {
+		params.BeforeID = uuid.NullUUID{UUID: before.cursor.ID, Valid: true}
+		params.BeforePublishedAt = sql.NullTime{Time: before.cursor.PublishedAt, Valid: true}
+	}
+	if after.cursor != nil {
+		params.AfterID = uuid.NullUUID{UUID: after.cursor.ID, Valid: true}
+		params.AfterPublishedAt = sql.NullTime{Time: after.cursor.PublishedAt, Valid: true}
+	}
+	posts, err := s.db.GetPostForUser(context.Background(), params)
 	if err != nil {
 		return err
 	}
+	// posts newer than the after cursor come oldest first
+	if after.cursor != nil {
+		slices.Reverse(posts)
+	}
 
+	if s.output != outputText {
+		var records []postRecord
+		for _, post := range posts {
+			records = append(records, postRecord{
+				ID: post.ID,
+				CreatedAt: post.CreatedAt,
+				UpdatedAt: post.UpdatedAt,
+				PublishedAt: nullTime(post.PublishedAt.Time, post.PublishedAt.Valid),
+				FeedID: post.FeedID,
+				FeedName: post.FeedName,
+				Title: post.Title.String,
+				URL: post.Url,
+				Description: nullString(post.Description.String, post.Description.Valid),
+				Content: nullString(post.Content.String, post.Content.Valid),
+				ArchivePath: nullString(post.ArchivePath.String, post.ArchivePath.Valid),
+				ReadAt: nullTime(post.ReadAt.Time, post.ReadAt.Valid),
+				StarredAt: nullTime(post.StarredAt.Time, post.StarredAt.Valid),
+				Cursor: browseCursor(post.PublishedAt, post.CreatedAt, post.ID).String(),
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+
+	width := terminalWidth()
 	for _, post := range posts {
-		fmt.Printf("title: %v\n", post.Title)
+		fmt.Printf("title: %v\n", post.Title.String)
+		fmt.Printf("id: %s\n", post.ID)
 		fmt.Printf("link: %s\n", post.Url)
-		fmt.Printf("item description: %v\n", post.Description)
-		fmt.Printf("item publication date: %v\n", post.PublishedAt)
+		if post.Content.Valid {
+			fmt.Printf("item content:\n%s\n", renderHTML(post.Content.String, width))
+		} else {
+			fmt.Printf("item description:\n%s\n", renderHTML(post.Description.String, width))
+		}
+		fmt.Printf("item publication date: %v\n", post.PublishedAt.Time)
+		if post.ArchivePath.Valid {
+			fmt.Printf("archived copy: file://%s\n", post.ArchivePath.String)
+		}
+		if post.ReadAt.Valid {
+			fmt.Printf("read at: %v\n", post.ReadAt.Time)
+		}
+		if post.StarredAt.Valid {
+			fmt.Printf("starred at: %v\n", post.StarredAt.Time)
+		}
+	}
+
+	full := int32(len(posts)) == limit
+	if len(posts) > 0 && (before.cursor != nil || *page > 1 || (after.cursor != nil && full)) {
+		fmt.Printf("previous: browse --after %s\n", browseCursor(posts[0].PublishedAt, posts[0].CreatedAt, posts[0].ID))
+	}
+	// there are older posts than the after cursor
+	if len(posts) > 0 && (full || after.cursor != nil) {
+		fmt.Printf("next: browse --before %s\n", browseCursor(posts[len(posts)-1].PublishedAt, posts[len(posts)-1].CreatedAt, posts[len(posts)-1].ID))
 	}
 return nil
 }
+
+// browseCursor returns the cursor of a post in the order of browse.
+func browseCursor(publishedAt sql.NullTime, createdAt time.Time, id uuid.UUID) postCursor {
+	if publishedAt.Valid {
+		return postCursor{PublishedAt: publishedAt.Time, ID: id}
+	}
+	return postCursor{PublishedAt: createdAt, ID: id}
+}
+
+func handlerFulltext(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 2 {
+		return errors.New("please provide the url of the feed and on or off")
+	}
+	url := cmd.args[0]
+
+	var fulltext bool
+	switch cmd.args[1] {
+	case "on":
+		fulltext = true
+	case "off":
+		fulltext = false
+	default:
+		return fmt.Errorf("unknown option %s, please use on or off", cmd.args[1])
+	}
+
+	feed, err := resolveFeed(s, url)
+	if err != nil {
+		return err
+	}
+	if feed.UserID != user.ID {
+		return errors.New("only the user who added the feed can change it")
+	}
+
+	err = s.db.SetFeedFulltext(context.Background(), database.SetFeedFulltextParams{
+		Fulltext: fulltext,
+		UpdatedAt: time.Now().UTC(),
+		ID: feed.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't update feed: %w", err)
+	}
+
+	fmt.Printf("full articles for %s: %s\n", feed.Name, cmd.args[1])
+	return nil
+}
+
+// resolveFeed finds a feed by its url, in any form getFeedByURL accepts,
+// its id, its name or the start of its name.
+// Names only have to match case insensitively if no feed has the exact
+// name. Refs matching several feeds are an error listing the candidates.
+func resolveFeed(s *state, ref string) (database.Feed, error) {
+	feed, err := s.db.GetFeed(context.Background(), ref)
+	if err == nil {
+		return feed, nil
+	}
+	feed, err = getFeedByURL(s, ref)
+	if err == nil {
+		return feed, nil
+	}
+	if id, err := uuid.Parse(ref); err == nil {
+		feed, err := s.db.GetFeedByID(context.Background(), id)
+		if err == nil {
+			return feed, nil
+		}
+	}
+
+	feeds, err := s.db.GetFeedsByName(context.Background(), ref)
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("couldn't find feed %s: %w", ref, err)
+	}
+	if len(feeds) == 0 {
+		feeds, err = s.db.GetFeedsByNamePrefix(context.Background(), escapeLike(ref)+"%")
+		if err != nil {
+			return database.Feed{}, fmt.Errorf("couldn't find feed %s: %w", ref, err)
+		}
+		// "Blog" picks blog rather than blog-archive
+		exact := slices.DeleteFunc(slices.Clone(feeds), func(feed database.Feed) bool {
+			return !strings.EqualFold(feed.Name, ref)
+		})
+		if len(exact) > 0 {
+			feeds = exact
+		}
+	}
+
+	switch len(feeds) {
+	case 0:
+		return database.Feed{}, fmt.Errorf("couldn't find feed %s", ref)
+	case 1:
+		return feeds[0], nil
+	}
+	var candidates []string
+	for _, feed := range feeds {
+		candidates = append(candidates, fmt.Sprintf("%s (%s)", feed.Name, feed.Url))
+	}
+	return database.Feed{}, fmt.Errorf("%s matches several feeds, please use the url: %s", ref, strings.Join(candidates, ", "))
+}
+
+// resolveFollow finds a feed followed by user like resolveFeed and
+// returns it with the follow.
+func resolveFollow(s *state, user database.User, ref string) (database.Feed, database.FeedFollow, error) {
+	feed, err := resolveFeed(s, ref)
+	if err != nil {
+		return database.Feed{}, database.FeedFollow{}, err
+	}
+	follow, err := s.db.GetFeedFollow(context.Background(), database.GetFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, database.FeedFollow{}, fmt.Errorf("%s doesn't follow %s", user.Name, feed.Name)
+	}
+	if err != nil {
+		return database.Feed{}, database.FeedFollow{}, fmt.Errorf("couldn't get feed follow: %w", err)
+	}
+	return feed, follow, nil
+}
+
+// nonEmpty returns the values that aren't empty.
+func nonEmpty(values ...string) []string {
+	var kept []string
+	for _, value := range values {
+		if value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// resolvePost finds a post by its id or url.
+func resolvePost(s *state, ref string) (database.Post, error) {
+	id, err := uuid.Parse(ref)
+	if err == nil {
+		return s.db.GetPostByID(context.Background(), id)
+	}
+	// links are stored cleaned up, posts stored before that as they were
+	post, err := s.db.GetPostByURL(context.Background(), canonicalPostURL(ref))
+	if errors.Is(err, sql.ErrNoRows) && canonicalPostURL(ref) != ref {
+		return s.db.GetPostByURL(context.Background(), ref)
+	}
+	return post, err
+}
+
+func handlerRead(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return errors.New("please provide the id or url of a post")
+	}
+
+	for _, ref := range cmd.args {
+		post, err := resolvePost(s, ref)
+		if err != nil {
+			return fmt.Errorf("couldn't find post %s", ref)
+		}
+		err = s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+			ReadAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't mark post as read: %w", err)
+		}
+		fmt.Printf("marked %s as read\n", post.Url)
+	}
+	return nil
+}
+
+func handlerUnread(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return errors.New("please provide the id or url of a post")
+	}
+
+	for _, ref := range cmd.args {
+		post, err := resolvePost(s, ref)
+		if err != nil {
+			return fmt.Errorf("couldn't find post %s", ref)
+		}
+		err = s.db.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't mark post as unread: %w", err)
+		}
+		fmt.Printf("marked %s as unread\n", post.Url)
+	}
+	return nil
+}
+
+func handlerMarkAll(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd.name)
+	feedURL := fs.String("feed", "", "only mark posts of this feed")
+	before := fs.String("before", "", "only mark posts published before this date")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || args[0] != "read" {
+		return errors.New("usage: markall read [--feed url] [--before date]")
+	}
+
+	params := database.MarkAllPostsReadParams{
+		ReadAt: time.Now().UTC(),
+		UserID: user.ID,
+	}
+	if *feedURL != "" {
+		feed, err := resolveFeed(s, *feedURL)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if *before != "" {
+		t, err := parseTimeFlag(*before, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		params.Before = sql.NullTime{Time: t, Valid: true}
+	}
+
+	marked, err := s.db.MarkAllPostsRead(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't mark posts as read: %w", err)
+	}
+	fmt.Printf("marked %d posts as read\n", marked)
+	return nil
+}
+
+func handlerStar(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return errors.New("please provide the id or url of a post")
+	}
+
+	for _, ref := range cmd.args {
+		post, err := resolvePost(s, ref)
+		if err != nil {
+			return fmt.Errorf("couldn't find post %s", ref)
+		}
+		err = s.db.StarPost(context.Background(), database.StarPostParams{
+			UserID: user.ID,
+			PostID: post.ID,
+			StarredAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't star post: %w", err)
+		}
+		fmt.Printf("starred %s\n", post.Url)
+	}
+	return nil
+}
+
+func handlerUnstar(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return errors.New("please provide the id or url of a post")
+	}
+
+	for _, ref := range cmd.args {
+		post, err := resolvePost(s, ref)
+		if err != nil {
+			return fmt.Errorf("couldn't find post %s", ref)
+		}
+		err = s.db.UnstarPost(context.Background(), database.UnstarPostParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't unstar post: %w", err)
+		}
+		fmt.Printf("unstarred %s\n", post.Url)
+	}
+	return nil
+}
+
+func handlerStarred(s *state, cmd command, user database.User) error {
+	posts, err := s.db.GetStarredPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get starred posts: %w", err)
+	}
+
+	if s.output != outputText {
+		var records []postRecord
+		for _, post := range posts {
+			records = append(records, postRecord{
+				ID: post.ID,
+				CreatedAt: post.CreatedAt,
+				UpdatedAt: post.UpdatedAt,
+				PublishedAt: nullTime(post.PublishedAt.Time, post.PublishedAt.Valid),
+				FeedID: post.FeedID,
+				FeedName: post.FeedName,
+				Title: post.Title.String,
+				URL: post.Url,
+				Description: nullString(post.Description.String, post.Description.Valid),
+				Content: nullString(post.Content.String, post.Content.Valid),
+				ArchivePath: nullString(post.ArchivePath.String, post.ArchivePath.Valid),
+				ReadAt: nullTime(post.ReadAt.Time, post.ReadAt.Valid),
+				StarredAt: &post.StarredAt,
+				Cursor: browseCursor(post.PublishedAt, post.CreatedAt, post.ID).String(),
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+
+	if len(posts) == 0 {
+		fmt.Println("no starred posts")
+		return nil
+	}
+
+	fmt.Printf("user: %s starred these posts:\n", user.Name)
+	for _, post := range posts {
+		fmt.Printf("title: %s\n", post.Title.String)
+		fmt.Printf("id: %s\n", post.ID)
+		fmt.Printf("link: %s\n", post.Url)
+		fmt.Printf("feed: %s\n", post.FeedName)
+		fmt.Printf("starred at: %v\n", post.StarredAt)
+		if post.ArchivePath.Valid {
+			fmt.Printf("archived copy: file://%s\n", post.ArchivePath.String)
+		}
+	}
+	return nil
+}
\ No newline at end of file
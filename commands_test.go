@@ -0,0 +1,1225 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arglp/gator/internal/config"
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func newTestState(t *testing.T) (*state, *fakeQuerier) {
+	t.Helper()
+	// config.SetUser persists to the home directory, keep it out of the real one
+	t.Setenv("HOME", t.TempDir())
+	q := newFakeQuerier()
+	return &state{
+		db:  q,
+		cfg: &config.Config{},
+	}, q
+}
+
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	err = f()
+	w.Close()
+	return <-done, err
+}
+
+func seedUser(q *fakeQuerier, name string) database.User {
+	user := database.User{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name:      name,
+	}
+	q.users = append(q.users, user)
+	return user
+}
+
+func seedFeed(q *fakeQuerier, owner database.User, name, url string) database.Feed {
+	feed := database.Feed{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name:      name,
+		Url:       url,
+		UserID:    owner.ID,
+	}
+	q.feeds = append(q.feeds, feed)
+	return feed
+}
+
+func seedFollow(q *fakeQuerier, user database.User, feed database.Feed) database.FeedFollow {
+	follow := database.FeedFollow{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	}
+	q.follows = append(q.follows, follow)
+	return follow
+}
+
+func seedPost(q *fakeQuerier, feed database.Feed, title, url string, publishedAt time.Time) database.Post {
+	post := database.Post{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+		Title:       sql.NullString{String: title, Valid: true},
+		Url:         url,
+		Description: sql.NullString{String: title + " description", Valid: true},
+		PublishedAt: sql.NullTime{Time: publishedAt, Valid: true},
+		FeedID:      feed.ID,
+	}
+	q.posts = append(q.posts, post)
+	return post
+}
+
+type handlerTest struct {
+	name        string
+	currentUser string
+	output      outputFormat
+	setup       func(q *fakeQuerier)
+	args        []string
+	wantErr     string
+	wantOut     []string
+	notOut      []string
+	check       func(t *testing.T, s *state, q *fakeQuerier)
+}
+
+func runHandlerTests(t *testing.T, handler func(*state, command) error, tests []handlerTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, q := newTestState(t)
+			s.cfg.CurrentUserName = tt.currentUser
+			s.output = tt.output
+			if tt.setup != nil {
+				tt.setup(q)
+			}
+
+			out, err := captureStdout(t, func() error {
+				return handler(s, command{name: "test", args: tt.args})
+			})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, out)
+				}
+			}
+			for _, unwanted := range tt.notOut {
+				if strings.Contains(out, unwanted) {
+					t.Errorf("expected output not to contain %q, got:\n%s", unwanted, out)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, s, q)
+			}
+		})
+	}
+}
+
+func TestHandlerLogin(t *testing.T) {
+	runHandlerTests(t, handlerLogin, []handlerTest{
+		{
+			name:    "missing name",
+			wantErr: "please provide a user name",
+		},
+		{
+			name:    "unknown user",
+			args:    []string{"alice"},
+			wantErr: "user alice doesn't exist",
+		},
+		{
+			name:    "existing user",
+			setup:   func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:    []string{"alice"},
+			wantOut: []string{"alice has been set as active user"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if s.cfg.CurrentUserName != "alice" {
+					t.Errorf("expected current user alice, got %q", s.cfg.CurrentUserName)
+				}
+			},
+		},
+	})
+}
+
+func TestHandlerRegister(t *testing.T) {
+	runHandlerTests(t, handlerRegister, []handlerTest{
+		{
+			name:    "missing name",
+			wantErr: "please provide a username to register",
+		},
+		{
+			name:    "duplicate user",
+			setup:   func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:    []string{"alice"},
+			wantErr: "username alice already exists",
+		},
+		{
+			name:    "new user",
+			args:    []string{"bob"},
+			wantOut: []string{"registered new user bob"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.users) != 1 || q.users[0].Name != "bob" {
+					t.Errorf("expected bob to be stored, got %v", q.users)
+				}
+				if s.cfg.CurrentUserName != "bob" {
+					t.Errorf("expected current user bob, got %q", s.cfg.CurrentUserName)
+				}
+			},
+		},
+	})
+}
+
+func TestHandlerReset(t *testing.T) {
+	runHandlerTests(t, handlerReset, []handlerTest{
+		{
+			name:  "deletes users",
+			setup: func(q *fakeQuerier) { seedUser(q, "alice") },
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.users) != 0 {
+					t.Errorf("expected no users, got %d", len(q.users))
+				}
+			},
+		},
+		{
+			name: "deletes feeds",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				seedFollow(q, alice, blog)
+				seedPost(q, blog, "post", "https://example.com/1", time.Now())
+			},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.users) != 0 || len(q.feeds) != 0 || len(q.posts) != 0 {
+					t.Errorf("expected no users, feeds and posts, got %d, %d, %d", len(q.users), len(q.feeds), len(q.posts))
+				}
+			},
+		},
+	})
+}
+
+func TestHandlerUsers(t *testing.T) {
+	runHandlerTests(t, handlerUsers, []handlerTest{
+		{
+			name:    "no users",
+			wantOut: []string{"no users registered"},
+		},
+		{
+			name:        "marks current user",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				seedUser(q, "alice")
+				seedUser(q, "bob")
+			},
+			wantOut: []string{"alice (current)", "bob\n"},
+			notOut:  []string{"bob (current)"},
+		},
+	})
+}
+
+func TestHandlerAddFeed(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerAddFeed), []handlerTest{
+		{
+			name:    "not logged in",
+			args:    []string{"blog", "https://example.com/rss"},
+			wantErr: "couldn't find user",
+		},
+		{
+			name:        "missing url",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:        []string{"blog"},
+			wantErr:     "please provide name and url",
+		},
+		{
+			name:        "adds and follows",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:        []string{"blog", "https://example.com/rss"},
+			wantOut:     []string{"added feed:", "blog", "https://example.com/rss"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.feeds) != 1 {
+					t.Fatalf("expected 1 feed, got %d", len(q.feeds))
+				}
+				if len(q.follows) != 1 || q.follows[0].FeedID != q.feeds[0].ID {
+					t.Errorf("expected the new feed to be followed, got %v", q.follows)
+				}
+			},
+		},
+		{
+			name:        "duplicate url",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				seedFeed(q, alice, "blog", "https://example.com/rss")
+			},
+			args:    []string{"blog", "http://Example.com:80/rss/?utm_source=x"},
+			wantErr: "the feed blog already has the url https://example.com/rss, please follow it instead",
+		},
+		{
+			name:        "canonical url",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:        []string{"blog", "HTTPS://Example.COM:443/rss/?b=2&utm_medium=feed&a=1#top"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if q.feeds[0].Url != "https://example.com/rss?a=1&b=2" {
+					t.Errorf("expected the canonical url, got %s", q.feeds[0].Url)
+				}
+			},
+		},
+		{
+			name:        "invalid url",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:        []string{"blog", "example.com/rss"},
+			wantErr:     "invalid url example.com/rss",
+		},
+	})
+}
+
+func TestHandlerFeeds(t *testing.T) {
+	runHandlerTests(t, handlerFeeds, []handlerTest{
+		{
+			name: "lists feeds with owner",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				seedFeed(q, alice, "blog", "https://example.com/rss")
+			},
+			wantOut: []string{"name: blog, url: https://example.com/rss, user: alice, followers: 0"},
+		},
+		{
+			name: "shows the channel",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				seedFeed(q, alice, "blog", "https://example.com/rss")
+				seedFeed(q, alice, "news", "https://news.example.com/rss")
+				q.feeds[0].Title = sql.NullString{String: "The Example Blog", Valid: true}
+				q.feeds[0].Description = sql.NullString{String: "all about examples", Valid: true}
+				q.feeds[0].SiteUrl = sql.NullString{String: "https://example.com/", Valid: true}
+				q.feeds[0].Language = sql.NullString{String: "en", Valid: true}
+				q.feeds[0].ImageUrl = sql.NullString{String: "https://example.com/logo.png", Valid: true}
+				q.feeds[0].Generator = sql.NullString{String: "Hugo", Valid: true}
+			},
+			wantOut: []string{
+				"followers: 0\n  The Example Blog - all about examples\n  site: https://example.com/, language: en, image: https://example.com/logo.png, generator: Hugo\n",
+				"name: news, url: https://news.example.com/rss, user: alice, followers: 0\n",
+			},
+			notOut: []string{"followers: 0\n  \n"},
+		},
+		{
+			name: "shortens the description",
+			setup: func(q *fakeQuerier) {
+				seedFeed(q, seedUser(q, "alice"), "blog", "https://example.com/rss")
+				q.feeds[0].Title = sql.NullString{String: "The Example\nBlog", Valid: true}
+				q.feeds[0].Description = sql.NullString{String: "all about\n\n  examples " + strings.Repeat("and more examples ", 10), Valid: true}
+			},
+			wantOut: []string{"followers: 0\n  The Example Blog - all about examples and more examples and more examples and more examples and more examples and more…\n"},
+		},
+	})
+}
+
+func TestHandlerFeedsSearchAndSort(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		bob := seedUser(q, "bob")
+		carol := seedUser(q, "carol")
+		golang := seedFeed(q, alice, "Go blog", "https://go.dev/blog/feed.atom")
+		news := seedFeed(q, bob, "news", "https://news.example.com/rss")
+		zines := seedFeed(q, bob, "zines", "https://zines.example.com/rss")
+		for i := range q.feeds {
+			q.feeds[i].CreatedAt = time.Date(2026, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		}
+		q.feeds[2].Description = sql.NullString{String: "Independent golang publishing", Valid: true}
+		seedFollow(q, alice, golang)
+		seedFollow(q, bob, news)
+		seedFollow(q, carol, news)
+		seedFollow(q, carol, zines)
+	}
+	lines := func(names ...string) []string {
+		var out []string
+		for _, name := range names {
+			out = append(out, "name: "+name+",")
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		user    string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{name: "search name, url and description", args: []string{"--search", "GO"}, want: lines("Go blog", "zines")},
+		{name: "search escapes wildcards", args: []string{"--search", "%"}, want: nil},
+		{name: "sort by followers", args: []string{"--sort", "followers"}, want: lines("news", "Go blog", "zines")},
+		{name: "sort by recent", args: []string{"--sort", "recent"}, want: lines("zines", "news", "Go blog")},
+		{name: "sort by name", args: []string{"--sort", "name"}, want: lines("Go blog", "news", "zines")},
+		{name: "limit", args: []string{"--sort", "name", "--limit", "1"}, want: lines("Go blog")},
+		{name: "popular leaves out followed feeds", user: "carol", args: []string{"--popular"}, want: lines("Go blog")},
+		{name: "popular without a user", args: []string{"--popular"}, wantErr: "feeds --popular needs an active user, please log in"},
+		{name: "unknown sort", args: []string{"--sort", "size"}, wantErr: "unknown sort size, please use followers, recent or name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, q := newTestState(t)
+			setup(q)
+			s.cfg.CurrentUserName = tt.user
+			out, err := captureStdout(t, func() error {
+				return handlerFeeds(s, command{name: "feeds", args: tt.args})
+			})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+				if name, _, ok := strings.Cut(line, " url: "); ok {
+					got = append(got, name)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandlerFollow(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerFollow), []handlerTest{
+		{
+			name:        "missing url",
+			currentUser: "bob",
+			setup:       func(q *fakeQuerier) { seedUser(q, "bob") },
+			wantErr:     "required more arguments",
+		},
+		{
+			name:        "unknown feed",
+			currentUser: "bob",
+			setup:       func(q *fakeQuerier) { seedUser(q, "bob") },
+			args:        []string{"https://example.com/rss"},
+			wantErr:     "couldn't find feed",
+		},
+		{
+			name:        "follows feed",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				seedUser(q, "bob")
+				seedFeed(q, alice, "blog", "https://example.com/rss")
+			},
+			args:    []string{"https://example.com/rss"},
+			wantOut: []string{"following new feed", "user: bob, feed: blog"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.follows) != 1 {
+					t.Errorf("expected 1 follow, got %d", len(q.follows))
+				}
+			},
+		},
+		{
+			name:        "already following",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				bob := seedUser(q, "bob")
+				feed := seedFeed(q, bob, "blog", "https://example.com/rss")
+				seedFollow(q, bob, feed)
+			},
+			args:    []string{"https://example.com/rss"},
+			wantErr: "couldn't follow",
+		},
+		{
+			name:        "already following one of several",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				bob := seedUser(q, "bob")
+				seedFeed(q, bob, "blog", "https://example.com/rss")
+				news := seedFeed(q, bob, "news", "https://news.example.com/rss")
+				seedFeed(q, bob, "zines", "https://zines.example.com/rss")
+				seedFollow(q, bob, news)
+			},
+			args:    []string{"blog", "news", "zines"},
+			wantErr: "couldn't follow news",
+			wantOut: []string{"feed: blog\n", "already following news\n", "feed: zines\n"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.follows) != 3 {
+					t.Errorf("expected the other feeds to be followed, got %d follows", len(q.follows))
+				}
+			},
+		},
+		{
+			name:        "by id, name and prefix",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				seedUser(q, "bob")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				blog.ID = uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+				q.feeds[0] = blog
+				seedFeed(q, alice, "news", "https://news.example.com/rss")
+				seedFeed(q, alice, "Security Advisories", "https://advisories.example.com/feed")
+			},
+			args:    []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "news", "security"},
+			wantOut: []string{"feed: blog\n", "feed: news\n", "feed: Security Advisories\n"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.follows) != 3 {
+					t.Errorf("expected 3 follows, got %d", len(q.follows))
+				}
+			},
+		},
+		{
+			name:        "case insensitive name before prefix",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				bob := seedUser(q, "bob")
+				seedFeed(q, bob, "blog archive", "https://example.com/archive.rss")
+				seedFeed(q, bob, "blog", "https://example.com/rss")
+			},
+			args:    []string{"Blog"},
+			wantOut: []string{"feed: blog\n"},
+		},
+		{
+			name:        "ambiguous prefix",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				bob := seedUser(q, "bob")
+				seedFeed(q, bob, "news", "https://news.example.com/rss")
+				seedFeed(q, bob, "blog", "https://example.com/rss")
+				seedFeed(q, bob, "newsletter", "https://letter.example.com/rss")
+			},
+			args:    []string{"blog", "ne"},
+			wantErr: "ne matches several feeds, please use the url: news (https://news.example.com/rss), newsletter (https://letter.example.com/rss)",
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.follows) != 0 {
+					t.Errorf("expected no follows, got %d", len(q.follows))
+				}
+			},
+		},
+	})
+}
+
+func TestHandlerFollowing(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerFollowing), []handlerTest{
+		{
+			name:        "lists followed feeds only",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				bob := seedUser(q, "bob")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				seedFeed(q, alice, "news", "https://news.example.com/rss")
+				seedFollow(q, bob, blog)
+			},
+			wantOut: []string{"user: bob is following these feeds:", "blog"},
+			notOut:  []string{"news"},
+		},
+		{
+			name:        "shows the channel",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				bob := seedUser(q, "bob")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				news := seedFeed(q, alice, "News", "https://news.example.com/rss")
+				q.feeds[0].Title = sql.NullString{String: "The Example Blog", Valid: true}
+				q.feeds[0].SiteUrl = sql.NullString{String: "https://example.com/", Valid: true}
+				q.feeds[0].Description = sql.NullString{String: "all about\nexamples " + strings.Repeat("and more examples ", 10), Valid: true}
+				q.feeds[0].Language = sql.NullString{String: "en", Valid: true}
+				q.feeds[0].ImageUrl = sql.NullString{String: "https://example.com/logo.png", Valid: true}
+				q.feeds[0].Generator = sql.NullString{String: "Hugo", Valid: true}
+				q.feeds[1].Title = sql.NullString{String: "News", Valid: true}
+				q.feeds[1].SiteUrl = sql.NullString{String: "https://news.example.com/", Valid: true}
+				seedFollow(q, bob, blog)
+				seedFollow(q, bob, news)
+			},
+			wantOut: []string{
+				"blog - The Example Blog, https://example.com/\n  all about examples and more examples and more examples and more examples and more examples and more…\n  language: en, image: https://example.com/logo.png, generator: Hugo\n",
+				"News - https://news.example.com/\n",
+			},
+			notOut: []string{"News - https://news.example.com/\n  "},
+		},
+		{
+			name:        "records with tags",
+			currentUser: "bob",
+			output:      outputCSV,
+			setup: func(q *fakeQuerier) {
+				bob := seedUser(q, "bob")
+				blog := seedFeed(q, bob, "blog", "https://example.com/rss")
+				news := seedFeed(q, bob, "news", "https://news.example.com/rss")
+				follow := seedFollow(q, bob, blog)
+				seedFollow(q, bob, news)
+				seedTag(q, follow, "work")
+				seedTag(q, follow, "go/news")
+			},
+			wantOut: []string{
+				",blog,https://example.com/rss,,,,,,,\"go/news, work\"\n",
+				",news,https://news.example.com/rss,,,,,,,\n",
+			},
+		},
+	})
+}
+
+func TestHandlerUnfollow(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerUnfollow), []handlerTest{
+		{
+			name:        "missing url",
+			currentUser: "bob",
+			setup:       func(q *fakeQuerier) { seedUser(q, "bob") },
+			wantErr:     "required more arguments",
+		},
+		{
+			name:        "unknown feed",
+			currentUser: "bob",
+			setup:       func(q *fakeQuerier) { seedUser(q, "bob") },
+			args:        []string{"https://example.com/rss"},
+			wantErr:     "couldn't find feed https://example.com/rss",
+		},
+		{
+			name:        "feed not followed",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				bob := seedUser(q, "bob")
+				seedFeed(q, bob, "blog", "https://example.com/rss")
+			},
+			args:    []string{"blog"},
+			wantErr: "bob doesn't follow blog",
+		},
+		{
+			name:        "unfollows several feeds by name",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				bob := seedUser(q, "bob")
+				seedFollow(q, bob, seedFeed(q, bob, "blog", "https://example.com/rss"))
+				seedFollow(q, bob, seedFeed(q, bob, "news", "https://news.example.com/rss"))
+				seedFollow(q, bob, seedFeed(q, bob, "fun", "https://fun.example.com/rss"))
+			},
+			args:    []string{"blog", "new"},
+			wantOut: []string{"unfollowed blog\nunfollowed news\n"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.follows) != 1 || q.follows[0].FeedID != q.feeds[2].ID {
+					t.Errorf("expected only the follow of fun, got %v", q.follows)
+				}
+			},
+		},
+		{
+			name:        "unfollows feed",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				bob := seedUser(q, "bob")
+				feed := seedFeed(q, bob, "blog", "https://example.com/rss")
+				seedFollow(q, bob, feed)
+			},
+			args: []string{"https://example.com/rss"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.follows) != 0 {
+					t.Errorf("expected no follows, got %d", len(q.follows))
+				}
+			},
+		},
+	})
+}
+
+func TestHandlerBrowse(t *testing.T) {
+	now := time.Now().UTC()
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+		seedFollow(q, alice, blog)
+		seedPost(q, blog, "oldest", "https://example.com/1", now.Add(-3*time.Hour))
+		seedPost(q, blog, "older", "https://example.com/2", now.Add(-2*time.Hour))
+		seedPost(q, blog, "newest", "https://example.com/3", now.Add(-1*time.Hour))
+		seedPost(q, news, "unfollowed", "https://news.example.com/1", now)
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerBrowse), []handlerTest{
+		{
+			name:        "default limit",
+			currentUser: "alice",
+			setup:       setup,
+			wantOut:     []string{"title: newest", "title: older"},
+			notOut:      []string{"title: oldest", "unfollowed"},
+		},
+		{
+			name:        "explicit limit",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"3"},
+			wantOut:     []string{"title: newest", "title: older", "title: oldest", "link: https://example.com/1"},
+			notOut:      []string{"unfollowed"},
+		},
+		{
+			name:        "invalid limit falls back to default",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"many"},
+			wantOut:     []string{"title: newest", "title: older"},
+			notOut:      []string{"title: oldest"},
+		},
+	})
+}
+
+func TestHandlerBrowseRendersHTML(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerBrowse), []handlerTest{
+		{
+			name:        "description as text",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				seedFollow(q, alice, blog)
+				seedPost(q, blog, "html", "https://example.com/1", time.Now())
+				q.posts[0].Description = sql.NullString{
+					String: `<p>see <a href="https://example.com/more">more</a></p>`,
+					Valid:  true,
+				}
+			},
+			wantOut: []string{"see more[1]", "[1] https://example.com/more"},
+			notOut:  []string{"<p>", "<a"},
+		},
+	})
+}
+
+func TestHandlerBrowsePagination(t *testing.T) {
+	s, q := newTestState(t)
+	s.cfg.CurrentUserName = "alice"
+	alice := seedUser(q, "alice")
+	blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+	seedFollow(q, alice, blog)
+	published := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 5; i++ {
+		seedPost(q, blog, fmt.Sprintf("post %d", i), fmt.Sprintf("https://example.com/%d", i), published.Add(time.Duration(i)*time.Hour))
+	}
+	// same publication date as post 3, the id decides the order
+	seedPost(q, blog, "post 3b", "https://example.com/3b", published.Add(3*time.Hour))
+
+	browse := func(args ...string) string {
+		t.Helper()
+		out, err := captureStdout(t, func() error {
+			return middlewareLoggedIn(handlerBrowse)(s, command{name: "browse", args: args})
+		})
+		if err != nil {
+			t.Fatalf("browse %v: %v", args, err)
+		}
+		return out
+	}
+	cursor := func(out, name string) string {
+		t.Helper()
+		for _, line := range strings.Split(out, "\n") {
+			if rest, ok := strings.CutPrefix(line, name+": browse --"); ok {
+				_, value, _ := strings.Cut(rest, " ")
+				return value
+			}
+		}
+		t.Fatalf("expected a %s cursor in:\n%s", name, out)
+		return ""
+	}
+
+	var seen []string
+	collect := func(out string) {
+		for _, line := range strings.Split(out, "\n") {
+			if title, ok := strings.CutPrefix(line, "title: "); ok {
+				seen = append(seen, title)
+			}
+		}
+	}
+
+	first := browse("2")
+	collect(first)
+	if strings.Contains(first, "previous: ") {
+		t.Errorf("expected no previous cursor on the first page, got:\n%s", first)
+	}
+
+	// new posts don't shift the following pages
+	seedPost(q, blog, "fresh", "https://example.com/fresh", published.Add(24*time.Hour))
+
+	second := browse("2", "--before", cursor(first, "next"))
+	collect(second)
+	third := browse("2", "--before", cursor(second, "next"))
+	collect(third)
+
+	if len(seen) != 6 || seen[0] != "post 5" || seen[1] != "post 4" || seen[4] != "post 2" || seen[5] != "post 1" {
+		t.Errorf("expected every post once in order, got %v", seen)
+	}
+	if !slices.Contains(seen[2:4], "post 3") || !slices.Contains(seen[2:4], "post 3b") {
+		t.Errorf("expected both posts published at the same time on the second page, got %v", seen)
+	}
+
+	back := browse("2", "--after", cursor(third, "previous"))
+	if back != second {
+		t.Errorf("expected --after to return the previous page\n%s\ngot\n%s", second, back)
+	}
+
+	paged := browse("2", "--page", "2")
+	// pages without a cursor count from the newest post
+	if strings.Contains(paged, "title: post 5") || !strings.Contains(paged, "title: post 4") {
+		t.Errorf("expected the second page to start at post 4, got:\n%s", paged)
+	}
+
+	out, err := captureStdout(t, func() error {
+		return middlewareLoggedIn(handlerBrowse)(s, command{name: "browse", args: []string{"--before", "nope"}})
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid cursor nope") {
+		t.Errorf("expected invalid cursor error, got %v: %s", err, out)
+	}
+}
+
+func TestHandlerBrowseFilters(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+		seedFollow(q, alice, blog)
+		seedFollow(q, alice, news)
+		seedPost(q, blog, "Kubernetes in production", "https://example.com/1", time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+		seedPost(q, blog, "postgres tips", "https://example.com/2", time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC))
+		seedPost(q, news, "headlines", "https://news.example.com/1", time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC))
+		q.posts[2].Description = sql.NullString{String: "<p>100% kubernetes</p>", Valid: true}
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerBrowse), []handlerTest{
+		{
+			name:        "by feed url",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"10", "--feed", "https://news.example.com/rss"},
+			wantOut:     []string{"title: headlines"},
+			notOut:      []string{"Kubernetes in production", "postgres tips"},
+		},
+		{
+			name:        "by feed name",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"10", "--feed", "blog"},
+			wantOut:     []string{"title: Kubernetes in production", "title: postgres tips"},
+			notOut:      []string{"headlines"},
+		},
+		{
+			name:        "unknown feed",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"--feed", "nope"},
+			wantErr:     "couldn't find feed nope",
+		},
+		{
+			name:        "ambiguous feed name",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				setup(q)
+				seedFeed(q, q.users[0], "blog", "https://blog.example.org/rss")
+			},
+			args:    []string{"--feed", "blog"},
+			wantErr: "blog matches several feeds, please use the url: blog (https://example.com/rss), blog (https://blog.example.org/rss)",
+		},
+		{
+			name:        "date range",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"10", "--since", "2026-08-01", "--until", "2026-10-01"},
+			wantOut:     []string{"title: Kubernetes in production"},
+			notOut:      []string{"postgres tips", "headlines"},
+		},
+		{
+			name:        "invalid date",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"--since", "yesterday"},
+			wantErr:     "invalid time yesterday",
+		},
+		{
+			name:        "keyword in title or description",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"10", "--match", "kubernetes"},
+			wantOut:     []string{"title: Kubernetes in production", "title: headlines"},
+			notOut:      []string{"postgres tips"},
+		},
+		{
+			name:        "keyword is not a pattern",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"10", "--match", "0%"},
+			wantOut:     []string{"title: headlines"},
+			notOut:      []string{"Kubernetes in production", "postgres tips"},
+		},
+		{
+			name:        "combined",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"10", "--feed", "blog", "--match", "kubernetes", "--since", "2026-08-01"},
+			wantOut:     []string{"title: Kubernetes in production"},
+			notOut:      []string{"postgres tips", "headlines"},
+		},
+	})
+}
+
+func TestHandlerFulltext(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		seedUser(q, "bob")
+		seedFeed(q, alice, "blog", "https://example.com/rss")
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerFulltext), []handlerTest{
+		{
+			name:        "missing option",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"https://example.com/rss"},
+			wantErr:     "please provide the url of the feed and on or off",
+		},
+		{
+			name:        "invalid option",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"https://example.com/rss", "maybe"},
+			wantErr:     "unknown option maybe",
+		},
+		{
+			name:        "not the owner",
+			currentUser: "bob",
+			setup:       setup,
+			args:        []string{"https://example.com/rss", "on"},
+			wantErr:     "only the user who added the feed can change it",
+		},
+		{
+			name:        "turns fulltext on",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"https://example.com/rss", "on"},
+			wantOut:     []string{"full articles for blog: on"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if !q.feeds[0].Fulltext {
+					t.Error("expected fulltext to be on")
+				}
+			},
+		},
+		{
+			name:        "url in another form",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"https://Example.com/rss/?utm_source=x", "on"},
+			wantOut:     []string{"full articles for blog: on"},
+		},
+	})
+}
+
+func seedRead(q *fakeQuerier, user database.User, post database.Post) {
+	q.reads = append(q.reads, database.PostRead{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: time.Now().UTC(),
+	})
+}
+
+func TestHandlerBrowseUnread(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		seedFollow(q, alice, blog)
+		read := seedPost(q, blog, "already read", "https://example.com/1", time.Now().Add(-time.Hour))
+		seedPost(q, blog, "unread", "https://example.com/2", time.Now().Add(-2*time.Hour))
+		seedRead(q, alice, read)
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerBrowse), []handlerTest{
+		{
+			name:        "unread by default",
+			currentUser: "alice",
+			setup:       setup,
+			wantOut:     []string{"title: unread"},
+			notOut:      []string{"already read"},
+		},
+		{
+			name:        "all posts",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"--all"},
+			wantOut:     []string{"title: unread", "title: already read", "read at: "},
+		},
+	})
+}
+
+func TestHandlerRead(t *testing.T) {
+	var post database.Post
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		seedFollow(q, alice, blog)
+		post = seedPost(q, blog, "post", "https://example.com/1", time.Now())
+		seedPost(q, blog, "other", "https://example.com/2", time.Now())
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerRead), []handlerTest{
+		{
+			name:        "missing post",
+			currentUser: "alice",
+			setup:       setup,
+			wantErr:     "please provide the id or url of a post",
+		},
+		{
+			name:        "unknown post",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"https://example.com/nope"},
+			wantErr:     "couldn't find post https://example.com/nope",
+		},
+		{
+			name:        "by copied link",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"https://Example.com/1?utm_source=newsletter"},
+			wantOut:     []string{"marked https://example.com/1 as read"},
+		},
+		{
+			name:        "by url stored before links were cleaned up",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				setup(q)
+				seedPost(q, q.feeds[0], "old", "https://Example.com/3", time.Now())
+			},
+			args:    []string{"https://Example.com/3"},
+			wantOut: []string{"marked https://Example.com/3 as read"},
+		},
+		{
+			name:        "by url",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"https://example.com/1"},
+			wantOut:     []string{"marked https://example.com/1 as read"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.reads) != 1 || q.reads[0].PostID != post.ID {
+					t.Errorf("expected post to be read, got %v", q.reads)
+				}
+			},
+		},
+		{
+			name:        "by id",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				setup(q)
+				q.posts[0].ID = uuid.MustParse("0d4c8a2e-6f1b-4b8e-9a53-1f0e2d3c4b5a")
+			},
+			args:    []string{"0d4c8a2e-6f1b-4b8e-9a53-1f0e2d3c4b5a"},
+			wantOut: []string{"marked https://example.com/1 as read"},
+		},
+	})
+}
+
+func TestHandlerUnread(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerUnread), []handlerTest{
+		{
+			name:        "marks unread",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				post := seedPost(q, blog, "post", "https://example.com/1", time.Now())
+				seedRead(q, alice, post)
+			},
+			args:    []string{"https://example.com/1"},
+			wantOut: []string{"marked https://example.com/1 as unread"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.reads) != 0 {
+					t.Errorf("expected no reads, got %v", q.reads)
+				}
+			},
+		},
+	})
+}
+
+func TestHandlerMarkAll(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+		other := seedFeed(q, alice, "other", "https://other.example.com/rss")
+		seedFollow(q, alice, blog)
+		seedFollow(q, alice, news)
+		seedPost(q, blog, "old", "https://example.com/1", time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+		seedPost(q, blog, "new", "https://example.com/2", time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC))
+		seedPost(q, news, "news", "https://news.example.com/1", time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+		seedPost(q, other, "unfollowed", "https://other.example.com/1", time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerMarkAll), []handlerTest{
+		{
+			name:        "missing read",
+			currentUser: "alice",
+			setup:       setup,
+			wantErr:     "usage: markall read",
+		},
+		{
+			name:        "all followed posts",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"read"},
+			wantOut:     []string{"marked 3 posts as read"},
+		},
+		{
+			name:        "by feed",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"read", "--feed", "https://example.com/rss"},
+			wantOut:     []string{"marked 2 posts as read"},
+		},
+		{
+			name:        "by feed url in another form",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"read", "--feed", "https://example.com/rss/"},
+			wantOut:     []string{"marked 2 posts as read"},
+		},
+		{
+			name:        "before date",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"read", "--feed", "https://example.com/rss", "--before", "2026-10-01"},
+			wantOut:     []string{"marked 1 posts as read"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.reads) != 1 || q.reads[0].PostID != q.posts[0].ID {
+					t.Errorf("expected only the old post to be read, got %v", q.reads)
+				}
+			},
+		},
+		{
+			name:        "invalid date",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"read", "--before", "yesterday"},
+			wantErr:     "invalid time yesterday",
+		},
+	})
+}
+
+func seedStar(q *fakeQuerier, user database.User, post database.Post) {
+	q.stars = append(q.stars, database.PostStar{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		StarredAt: time.Now().UTC(),
+	})
+}
+
+func TestHandlerStar(t *testing.T) {
+	var post database.Post
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		seedFollow(q, alice, blog)
+		post = seedPost(q, blog, "post", "https://example.com/1", time.Now())
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerStar), []handlerTest{
+		{
+			name:        "missing post",
+			currentUser: "alice",
+			setup:       setup,
+			wantErr:     "please provide the id or url of a post",
+		},
+		{
+			name:        "unknown post",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"https://example.com/nope"},
+			wantErr:     "couldn't find post https://example.com/nope",
+		},
+		{
+			name:        "stars post",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"https://example.com/1"},
+			wantOut:     []string{"starred https://example.com/1"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.stars) != 1 || q.stars[0].PostID != post.ID {
+					t.Errorf("expected post to be starred, got %v", q.stars)
+				}
+			},
+		},
+	})
+}
+
+func TestHandlerUnstar(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerUnstar), []handlerTest{
+		{
+			name:        "unstars post",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				post := seedPost(q, blog, "post", "https://example.com/1", time.Now())
+				seedStar(q, alice, post)
+			},
+			args:    []string{"https://example.com/1"},
+			wantOut: []string{"unstarred https://example.com/1"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.stars) != 0 {
+					t.Errorf("expected no stars, got %v", q.stars)
+				}
+			},
+		},
+	})
+}
+
+func TestHandlerStarred(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerStarred), []handlerTest{
+		{
+			name:        "no starred posts",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			wantOut:     []string{"no starred posts"},
+		},
+		{
+			name:        "lists starred posts of the user",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				bob := seedUser(q, "bob")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				mine := seedPost(q, blog, "mine", "https://example.com/1", time.Now())
+				theirs := seedPost(q, blog, "theirs", "https://example.com/2", time.Now())
+				seedStar(q, alice, mine)
+				seedStar(q, bob, theirs)
+			},
+			wantOut: []string{"title: mine", "feed: blog", "starred at: "},
+			notOut:  []string{"theirs"},
+		},
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// postCursor points at a post in the (published_at, id) order of browse.
+// Pages that start at a cursor don't shift when new posts are stored.
+type postCursor struct {
+	PublishedAt time.Time
+	ID          uuid.UUID
+}
+
+func (c postCursor) String() string {
+	raw := c.PublishedAt.UTC().Format(time.RFC3339Nano) + " " + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parsePostCursor(s string) (postCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return postCursor{}, fmt.Errorf("invalid cursor %s", s)
+	}
+	published, id, ok := strings.Cut(string(raw), " ")
+	if !ok {
+		return postCursor{}, fmt.Errorf("invalid cursor %s", s)
+	}
+	publishedAt, err := time.Parse(time.RFC3339Nano, published)
+	if err != nil {
+		return postCursor{}, fmt.Errorf("invalid cursor %s", s)
+	}
+	postID, err := uuid.Parse(id)
+	if err != nil {
+		return postCursor{}, fmt.Errorf("invalid cursor %s", s)
+	}
+	return postCursor{PublishedAt: publishedAt, ID: postID}, nil
+}
+
+// cursorFlag is a flag.Value for an optional cursor.
+type cursorFlag struct {
+	cursor *postCursor
+}
+
+func (f *cursorFlag) String() string {
+	if f.cursor == nil {
+		return ""
+	}
+	return f.cursor.String()
+}
+
+func (f *cursorFlag) Set(value string) error {
+	cursor, err := parsePostCursor(value)
+	if err != nil {
+		return err
+	}
+	f.cursor = &cursor
+	return nil
+}
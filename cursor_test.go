@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPostCursor(t *testing.T) {
+	cursor := postCursor{
+		PublishedAt: time.Date(2026, 10, 1, 8, 30, 0, 123456000, time.UTC),
+		ID:          uuid.MustParse("0d4c8a2e-6f1b-4b8e-9a53-1f0e2d3c4b5a"),
+	}
+
+	got, err := parsePostCursor(cursor.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.PublishedAt.Equal(cursor.PublishedAt) || got.ID != cursor.ID {
+		t.Errorf("expected %v, got %v", cursor, got)
+	}
+
+	for _, invalid := range []string{"", "nope", "bm9wZQ", "MjAyNi0xMC0wMSBub3Bl"} {
+		if _, err := parsePostCursor(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
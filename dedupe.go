@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+)
+
+// handlerDedupe finds feeds whose urls are the same once canonicalized,
+// over http or https, and with --merge merges the duplicates the user may
+// change into one feed.
+func handlerDedupe(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd.name)
+	merge := fs.Bool("merge", false, "merge the duplicate feeds")
+	_, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("couldn't get feeds: %w", err)
+	}
+
+	// feeds are ordered by creation, the first one of a group is the oldest
+	var keys []string
+	groups := map[string][]database.GetFeedsRow{}
+	for _, feed := range feeds {
+		canonical, err := canonicalURL(feed.Url)
+		if err != nil {
+			continue
+		}
+		// http and https urls are the same feed
+		_, key, _ := strings.Cut(canonical, "://")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], feed)
+	}
+
+	// like for feed chown, feeds of the system user may be changed by anyone
+	mayChange := func(feed database.GetFeedsRow) bool {
+		return feed.UserID == user.ID || feed.UserID == systemUserID
+	}
+
+	duplicates, others := 0, 0
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		keep := dedupeSurvivor(group)
+		fmt.Printf("%s:\n", key)
+		fmt.Printf("  keeping %s (%s) of %s\n", keep.Name, keep.Url, keep.UserName)
+		for _, feed := range group {
+			if feed.ID == keep.ID {
+				continue
+			}
+			if !mayChange(feed) {
+				others++
+				fmt.Printf("  duplicate %s (%s) of %s, only %s can merge it\n", feed.Name, feed.Url, feed.UserName, feed.UserName)
+				continue
+			}
+			duplicates++
+			if !*merge {
+				fmt.Printf("  duplicate %s (%s) of %s\n", feed.Name, feed.Url, feed.UserName)
+				continue
+			}
+			err = mergeFeed(s, feed, keep)
+			if err != nil {
+				return err
+			}
+		}
+		if *merge && mayChange(keep) {
+			err = canonicalizeFeedURL(s, keep)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case duplicates == 0 && others == 0:
+		fmt.Println("no duplicate feeds")
+	case duplicates > 0 && *merge:
+		fmt.Printf("merged %d duplicate feeds\n", duplicates)
+	case duplicates > 0:
+		fmt.Printf("found %d duplicate feeds, run dedupe --merge to merge them\n", duplicates)
+	}
+	if others > 0 {
+		fmt.Printf("found %d duplicate feeds of other users, only their owners can merge them\n", others)
+	}
+	return nil
+}
+
+// dedupeSurvivor picks the feed a group of duplicates is merged into: the
+// oldest https feed, or the oldest feed if none uses https.
+func dedupeSurvivor(group []database.GetFeedsRow) database.GetFeedsRow {
+	for _, feed := range group {
+		if strings.HasPrefix(strings.ToLower(feed.Url), "https://") {
+			return feed
+		}
+	}
+	return group[0]
+}
+
+// mergeFeed moves the followers and posts of feed to keep and removes
+// feed, all or nothing. Users following both feeds lose the tags and rules
+// of the follow of feed.
+func mergeFeed(s *state, feed, keep database.GetFeedsRow) error {
+	var follows, posts int64
+	err := s.inTx(func(q database.Querier) error {
+		var err error
+		follows, err = q.MoveFeedFollows(context.Background(), database.MoveFeedFollowsParams{
+			ToFeedID:   keep.ID,
+			UpdatedAt:  time.Now().UTC(),
+			FromFeedID: feed.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't move the followers of %s: %w", feed.Name, err)
+		}
+		posts, err = q.MovePosts(context.Background(), database.MovePostsParams{
+			ToFeedID:   keep.ID,
+			UpdatedAt:  time.Now().UTC(),
+			FromFeedID: feed.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't move the posts of %s: %w", feed.Name, err)
+		}
+		err = q.DeleteFeed(context.Background(), feed.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't remove %s: %w", feed.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  merged %s (%s), moved %d followers and %d posts\n", feed.Name, feed.Url, follows, posts)
+	return nil
+}
+
+// canonicalizeFeedURL stores the url of feed in its canonical form once
+// the duplicates that might have had it are gone.
+func canonicalizeFeedURL(s *state, feed database.GetFeedsRow) error {
+	canonical, err := canonicalURL(feed.Url)
+	if err != nil || canonical == feed.Url {
+		return nil
+	}
+	err = changeFeedURL(s, feed.ID, feed.Name, canonical)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  changed the url of %s to %s\n", feed.Name, canonical)
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHandlerDedupe(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		bob := seedUser(q, "bob")
+		old := seedFeed(q, alice, "old blog", "http://Example.com/feed/")
+		blog := seedFeed(q, bob, "blog", "https://example.com/feed?utm_source=x")
+		tracked := seedFeed(q, bob, "tracked", "https://example.com/feed?fbclid=1")
+		seedFeed(q, bob, "news", "https://news.example.com/rss")
+		seedFollow(q, alice, old)
+		seedFollow(q, bob, old)
+		seedFollow(q, bob, blog)
+		seedFollow(q, alice, tracked)
+		seedPost(q, old, "old post", "https://example.com/1", time.Now())
+		seedPost(q, tracked, "tracked post", "https://example.com/2", time.Now())
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerDedupe), []handlerTest{
+		{
+			name:        "lists duplicates",
+			currentUser: "bob",
+			setup:       setup,
+			wantOut: []string{
+				"example.com/feed:\n",
+				"  keeping blog (https://example.com/feed?utm_source=x) of bob\n",
+				"  duplicate old blog (http://Example.com/feed/) of alice, only alice can merge it\n",
+				"  duplicate tracked (https://example.com/feed?fbclid=1) of bob\n",
+				"found 1 duplicate feeds, run dedupe --merge to merge them\n",
+				"found 1 duplicate feeds of other users, only their owners can merge them\n",
+			},
+			notOut: []string{"news"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.feeds) != 4 {
+					t.Errorf("expected the feeds to be kept, got %d", len(q.feeds))
+				}
+			},
+		},
+		{
+			name:        "merges own duplicates",
+			currentUser: "bob",
+			setup:       setup,
+			args:        []string{"--merge"},
+			wantOut: []string{
+				"  merged tracked (https://example.com/feed?fbclid=1), moved 1 followers and 1 posts\n",
+				"  changed the url of blog to https://example.com/feed\n",
+				"merged 1 duplicate feeds\n",
+				"found 1 duplicate feeds of other users",
+			},
+			notOut: []string{"merged old blog"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.feeds) != 3 || q.feeds[0].Name != "old blog" || q.feeds[1].Url != "https://example.com/feed" {
+					t.Fatalf("unexpected feeds %v", q.feeds)
+				}
+				blog := q.feeds[1].ID
+				for _, post := range q.posts {
+					if post.Title.String == "tracked post" && post.FeedID != blog {
+						t.Errorf("expected the post to be moved to blog, got %v", post)
+					}
+				}
+			},
+		},
+		{
+			name:        "merges into a feed of another user",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"--merge"},
+			wantOut: []string{
+				"  merged old blog (http://Example.com/feed/), moved 1 followers and 1 posts\n",
+				"merged 1 duplicate feeds\n",
+				"  duplicate tracked (https://example.com/feed?fbclid=1) of bob, only bob can merge it\n",
+			},
+			notOut: []string{"changed the url"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.feeds) != 3 || q.feeds[0].Name != "blog" || q.feeds[0].Url != "https://example.com/feed?utm_source=x" {
+					t.Fatalf("unexpected feeds %v", q.feeds)
+				}
+				blog := q.feeds[0].ID
+				for _, follow := range q.follows {
+					if follow.FeedID != blog && follow.FeedID != q.feeds[1].ID {
+						t.Errorf("expected the follows of old blog to be moved to blog, got %v", follow)
+					}
+				}
+			},
+		},
+		{
+			name:        "merges feeds of the system user",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				system := seedUser(q, systemUserName)
+				system.ID = systemUserID
+				q.users[1].ID = systemUserID
+				seedFeed(q, alice, "blog", "https://example.com/feed")
+				seedFeed(q, system, "orphan", "http://example.com/feed")
+			},
+			args:    []string{"--merge"},
+			wantOut: []string{"  merged orphan (http://example.com/feed), moved 0 followers and 0 posts\n"},
+		},
+		{
+			name:        "no duplicates",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedFeed(q, seedUser(q, "alice"), "blog", "https://example.com/rss") },
+			wantOut:     []string{"no duplicate feeds"},
+		},
+	})
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// posts in a digest, more than enough for a day of news
+	digestPostLimit  = 1000
+	digestExcerptLen = 200
+)
+
+var digestFormats = []string{"markdown", "html", "text"}
+
+type digest struct {
+	User  string
+	Since time.Time
+	Feeds []digestFeed
+}
+
+type digestFeed struct {
+	Name  string
+	Posts []digestPost
+}
+
+type digestPost struct {
+	Title       string
+	URL         string
+	PublishedAt time.Time
+	Excerpt     string
+}
+
+var digestFuncs = template.FuncMap{
+	"date": func(t time.Time) string {
+		return t.Local().Format("2006-01-02 15:04")
+	},
+	"mdlink": func(s string) string {
+		return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(s)
+	},
+	"underline": func(s string) string {
+		return strings.Repeat("=", utf8.RuneCountInString(s))
+	},
+}
+
+var markdownDigest = template.Must(template.New("markdown").Funcs(digestFuncs).Parse(`# Digest for {{.User}}
+
+New posts since {{date .Since}}.
+{{range .Feeds}}
+## {{.Name}}
+{{range .Posts}}
+- [{{mdlink .Title}}](<{{.URL}}>) ({{date .PublishedAt}}){{if .Excerpt}}
+  {{.Excerpt}}{{end}}
+{{- end}}
+{{else}}
+No new posts.
+{{end}}`))
+
+var textDigest = template.Must(template.New("text").Funcs(digestFuncs).Parse(`Digest for {{.User}}, new posts since {{date .Since}}
+{{range .Feeds}}
+{{.Name}}
+{{underline .Name}}
+{{range .Posts}}
+* {{.Title}} ({{date .PublishedAt}})
+  {{.URL}}{{if .Excerpt}}
+  {{.Excerpt}}{{end}}
+{{end}}{{else}}
+No new posts.
+{{end}}`))
+
+var htmlDigest = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap(digestFuncs)).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Digest for {{.User}}</title>
+</head>
+<body>
+<h1>Digest for {{.User}}</h1>
+<p>New posts since {{date .Since}}.</p>
+{{range .Feeds}}<h2>{{.Name}}</h2>
+<ul>
+{{range .Posts}}<li><a href="{{.URL}}">{{.Title}}</a> ({{date .PublishedAt}}){{if .Excerpt}}<br>{{.Excerpt}}{{end}}</li>
+{{end}}</ul>
+{{else}}<p>No new posts.</p>
+{{end}}</body>
+</html>
+`))
+
+func handlerDigest(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd.name)
+	since := fs.String("since", "24h", "include posts published since this date")
+	format := fs.String("format", "markdown", "markdown, html or text")
+	out := fs.String("out", "", "write the digest to this file instead of stdout")
+	_, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(digestFormats, *format) {
+		return fmt.Errorf("unknown format %s, please use markdown, html or text", *format)
+	}
+
+	sinceTime, err := parseTimeFlag(*since, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	d, err := buildDigest(s, user, sinceTime, false)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("couldn't create %s: %w", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	err = writeDigest(w, *format, d)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Printf("wrote digest of %d feeds to %s\n", len(d.Feeds), *out)
+	}
+	return nil
+}
+
+// buildDigest collects the posts of the feeds user follows published
+// since the given time, grouped by feed. With stored the posts are
+// selected by when they were stored instead, so posts agg fetched after
+// the time don't miss the digest even if they were published before it.
+func buildDigest(s *state, user database.User, since time.Time, stored bool) (digest, error) {
+	params := database.GetPostForUserParams{
+		UserID:      user.ID,
+		IncludeRead: true,
+		Limit:       digestPostLimit,
+	}
+	if stored {
+		params.StoredSince = sql.NullTime{Time: since, Valid: true}
+	} else {
+		params.Since = sql.NullTime{Time: since, Valid: true}
+	}
+	posts, err := s.db.GetPostForUser(context.Background(), params)
+	if err != nil {
+		return digest{}, fmt.Errorf("couldn't get posts: %w", err)
+	}
+
+	d := digest{User: user.Name, Since: since}
+	feeds := map[uuid.UUID]int{}
+	for _, post := range posts {
+		i, ok := feeds[post.FeedID]
+		if !ok {
+			i = len(d.Feeds)
+			feeds[post.FeedID] = i
+			d.Feeds = append(d.Feeds, digestFeed{Name: post.FeedName})
+		}
+
+		content := post.Description.String
+		if post.Content.Valid {
+			content = post.Content.String
+		}
+		title := post.Title.String
+		if title == "" {
+			title = post.Url
+		}
+		publishedAt := post.CreatedAt
+		if post.PublishedAt.Valid {
+			publishedAt = post.PublishedAt.Time
+		}
+		d.Feeds[i].Posts = append(d.Feeds[i].Posts, digestPost{
+			Title:       title,
+			URL:         post.Url,
+			PublishedAt: publishedAt,
+			Excerpt:     excerpt(snippetText(content), digestExcerptLen),
+		})
+	}
+	sort.SliceStable(d.Feeds, func(i, j int) bool {
+		return strings.ToLower(d.Feeds[i].Name) < strings.ToLower(d.Feeds[j].Name)
+	})
+	return d, nil
+}
+
+func writeDigest(w io.Writer, format string, d digest) error {
+	switch format {
+	case "markdown":
+		return markdownDigest.Execute(w, d)
+	case "html":
+		return htmlDigest.Execute(w, d)
+	case "text":
+		return textDigest.Execute(w, d)
+	}
+	return fmt.Errorf("unknown format %s, please use markdown, html or text", format)
+}
+
+// excerpt shortens text to at most n characters at a word boundary.
+func excerpt(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	cut := string(runes[:n])
+	// don't break the last word
+	if i := strings.LastIndexByte(cut, ' '); i > n/2 && runes[n] != ' ' {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
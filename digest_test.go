@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{text: "short", n: 10, want: "short"},
+		{text: "a few words that are too long", n: 16, want: "a few words that…"},
+		{text: "sentence ends here. and goes on", n: 20, want: "sentence ends here…"},
+		{text: "averyveryverylongword", n: 8, want: "averyver…"},
+	}
+	for _, tt := range tests {
+		got := excerpt(tt.text, tt.n)
+		if got != tt.want {
+			t.Errorf("excerpt(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestHandlerDigest(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		news := seedFeed(q, alice, "News", "https://news.example.com/rss")
+		other := seedFeed(q, alice, "other", "https://other.example.com/rss")
+		seedFollow(q, alice, blog)
+		seedFollow(q, alice, news)
+		seedPost(q, news, "headline [1]", "https://news.example.com/1", time.Now().Add(-time.Hour))
+		seedPost(q, blog, "fresh <post>", "https://example.com/1", time.Now().Add(-2*time.Hour))
+		seedPost(q, blog, "old post", "https://example.com/2", time.Now().Add(-48*time.Hour))
+		seedPost(q, other, "unfollowed", "https://other.example.com/1", time.Now())
+		q.posts[1].Description = sql.NullString{String: "<p>an <b>excerpt</b> of the post</p>", Valid: true}
+		seedRead(q, alice, q.posts[0])
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerDigest), []handlerTest{
+		{
+			name:        "markdown by default",
+			currentUser: "alice",
+			setup:       setup,
+			wantOut: []string{
+				"# Digest for alice",
+				"## blog\n\n- [fresh <post>](<https://example.com/1>) (",
+				"\n  an excerpt of the post\n",
+				"## News\n\n- [headline \\[1\\]](<https://news.example.com/1>)",
+			},
+			notOut: []string{"old post", "unfollowed"},
+		},
+		{
+			name:        "text",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"--format", "text"},
+			wantOut:     []string{"blog\n====\n", "News\n====\n", "* fresh <post> (", "  https://example.com/1\n  an excerpt of the post\n"},
+		},
+		{
+			name:        "html is escaped",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"--format", "html", "--since", "7d"},
+			wantOut:     []string{"<h2>blog</h2>", `<a href="https://example.com/1">fresh &lt;post&gt;</a>`, "old post"},
+		},
+		{
+			name:        "nothing new",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"--since", "1m"},
+			wantOut:     []string{"No new posts."},
+		},
+		{
+			name:        "unknown format",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"--format", "pdf"},
+			wantErr:     "unknown format pdf",
+		},
+	})
+}
+
+func TestHandlerDigestToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "digest.md")
+	runHandlerTests(t, middlewareLoggedIn(handlerDigest), []handlerTest{
+		{
+			name:        "writes file",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				seedFollow(q, alice, blog)
+				seedPost(q, blog, "fresh", "https://example.com/1", time.Now())
+			},
+			args:    []string{"--out", path},
+			wantOut: []string{"wrote digest of 1 feeds to " + path},
+			notOut:  []string{"# Digest"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(data), "- [fresh](<https://example.com/1>)") {
+					t.Errorf("unexpected digest:\n%s", data)
+				}
+			},
+		},
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+)
+
+func handlerEmail(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return errors.New("usage: email set <address> [--every daily|weekly|12h] [--format markdown|html|text] | email show | email off")
+	}
+
+	switch cmd.args[0] {
+	case "set":
+		return setEmailDigest(s, cmd.args[1:], user)
+	case "show":
+		settings, err := s.db.GetEmailDigest(context.Background(), user.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("no email digest set up")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't get email digest: %w", err)
+		}
+		fmt.Printf("email: %s\n", settings.Email)
+		fmt.Printf("schedule: %s\n", settings.Schedule)
+		fmt.Printf("format: %s\n", settings.Format)
+		if settings.LastSentAt.Valid {
+			fmt.Printf("last sent at: %v\n", settings.LastSentAt.Time)
+		}
+		return nil
+	case "off":
+		err := s.db.DeleteEmailDigest(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't turn off email digest: %w", err)
+		}
+		fmt.Println("email digest turned off")
+		return nil
+	}
+	return fmt.Errorf("unknown email command %s, please use set, show or off", cmd.args[0])
+}
+
+func setEmailDigest(s *state, args []string, user database.User) error {
+	fs := newFlagSet("email set")
+	every := fs.String("every", "daily", "daily, weekly or a duration like 12h")
+	format := fs.String("format", "html", "markdown, html or text")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return errors.New("please provide an email address")
+	}
+	address, err := mail.ParseAddress(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid email address %s", args[0])
+	}
+	_, err = parseSchedule(*every)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(digestFormats, *format) {
+		return fmt.Errorf("unknown format %s, please use markdown, html or text", *format)
+	}
+
+	settings, err := s.db.SetEmailDigest(context.Background(), database.SetEmailDigestParams{
+		UserID:    user.ID,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Email:     address.Address,
+		Schedule:  *every,
+		Format:    *format,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't set email digest: %w", err)
+	}
+	fmt.Printf("sending %s digests to %s %s\n", settings.Format, settings.Email, settings.Schedule)
+	return nil
+}
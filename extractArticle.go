@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// articles with less text than this are most likely not the article
+	minArticleLength   = 250
+	minParagraphLength = 25
+)
+
+var (
+	positiveHint = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negativeHint = regexp.MustCompile(`(?i)comment|sidebar|footer|footnote|masthead|menu|nav|promo|related|share|social|sponsor|widget|advert|banner|cookie|subscribe|popup|byline|breadcrumb`)
+
+	errNoArticle = errors.New("couldn't find the article on the page")
+)
+
+// elements that never are part of the article
+var unlikelyElements = map[atom.Atom]bool{
+	atom.Aside:    true,
+	atom.Button:   true,
+	atom.Footer:   true,
+	atom.Form:     true,
+	atom.Header:   true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Noscript: true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Textarea: true,
+}
+
+// fetchArticle fetches the page at pageURL and extracts its main content.
+func fetchArticle(ctx context.Context, pageURL string) (string, error) {
+	page, err := fetchURL(ctx, pageURL)
+	if err != nil {
+		return "", err
+	}
+	return extractArticle(page, pageURL)
+}
+
+// extractArticle finds the main content of an HTML page the way
+// readability does: paragraphs add to the score of their parent and
+// grandparent by the amount of text and commas they contain, class and id
+// hints and link density adjust the scores and the best element wins,
+// together with siblings that look like they belong to it. The result is
+// sanitized HTML with absolute urls.
+func extractArticle(page []byte, pageURL string) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	if href := findBaseHref(doc); href != "" {
+		if ref, err := url.Parse(href); err == nil {
+			base = base.ResolveReference(ref)
+		}
+	}
+
+	removeUnlikely(doc)
+
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	walkNodes(doc, func(n *html.Node) {
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		default:
+			return
+		}
+		text := textContent(n)
+		if len(text) < minParagraphLength {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		scores[candidate] *= 1 - linkDensity(candidate)
+		if best == nil || scores[candidate] > bestScore {
+			best = candidate
+			bestScore = scores[candidate]
+		}
+	}
+	if best == nil || len(textContent(best)) < minArticleLength {
+		return "", errNoArticle
+	}
+
+	// content is sometimes split over siblings, e.g. around an ad
+	threshold := max(10, bestScore*0.2)
+	var parts []*html.Node
+	for sibling := best.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling == best {
+			parts = append(parts, sibling)
+			continue
+		}
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if score, ok := scores[sibling]; ok && score >= threshold {
+			parts = append(parts, sibling)
+			continue
+		}
+		if sibling.DataAtom == atom.P {
+			text := textContent(sibling)
+			if len(text) > 80 && linkDensity(sibling) < 0.25 {
+				parts = append(parts, sibling)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, part := range parts {
+		absoluteURLs(part, base)
+		for _, clean := range sanitizeNode(part) {
+			html.Render(&buf, clean)
+		}
+	}
+	return buf.String(), nil
+}
+
+func walkNodes(n *html.Node, f func(*html.Node)) {
+	if n.Type == html.ElementNode {
+		f(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkNodes(c, f)
+	}
+}
+
+func findBaseHref(doc *html.Node) string {
+	href := ""
+	walkNodes(doc, func(n *html.Node) {
+		if n.DataAtom == atom.Base && href == "" {
+			href = attrValue(n, "href")
+		}
+	})
+	return href
+}
+
+// removeUnlikely removes the elements that can't be part of the article,
+// like navigation, scripts and comment sections.
+func removeUnlikely(doc *html.Node) {
+	var unlikely []*html.Node
+	walkNodes(doc, func(n *html.Node) {
+		if unlikelyElements[n.DataAtom] {
+			unlikely = append(unlikely, n)
+			return
+		}
+		switch n.DataAtom {
+		case atom.Html, atom.Body, atom.Article, atom.Main:
+			return
+		}
+		hints := attrValue(n, "class") + " " + attrValue(n, "id")
+		if negativeHint.MatchString(hints) && !positiveHint.MatchString(hints) {
+			unlikely = append(unlikely, n)
+		}
+	})
+	for _, n := range unlikely {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	for _, hints := range []string{attrValue(n, "class"), attrValue(n, "id")} {
+		if hints == "" {
+			continue
+		}
+		if negativeHint.MatchString(hints) {
+			score -= 25
+		}
+		if positiveHint.MatchString(hints) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// textContent returns the text of n with whitespace collapsed.
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// linkDensity returns the share of the text of n that is inside links.
+func linkDensity(n *html.Node) float64 {
+	length := len(textContent(n))
+	if length == 0 {
+		return 0
+	}
+	linkLength := 0
+	walkNodes(n, func(c *html.Node) {
+		if c.DataAtom == atom.A {
+			linkLength += len(textContent(c))
+		}
+	})
+	return float64(linkLength) / float64(length)
+}
+
+func absoluteURLs(n *html.Node, base *url.URL) {
+	walkNodes(n, func(c *html.Node) {
+		for i, attr := range c.Attr {
+			if attr.Key != "href" && attr.Key != "src" {
+				continue
+			}
+			ref, err := url.Parse(strings.TrimSpace(attr.Val))
+			if err != nil {
+				continue
+			}
+			c.Attr[i].Val = base.ResolveReference(ref).String()
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExtractArticle(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		url     string
+		want    []string
+		notWant []string
+		wantErr error
+	}{
+		{
+			name:    "blog post",
+			fixture: "testdata/articles/blog.html",
+			url:     "https://blog.example.com/posts/postgres.html",
+			want: []string{
+				"After three years of running Postgres",
+				"test your backups",
+				`<a href="https://blog.example.com/posts/pgbouncer.html">our pgbouncer setup</a>`,
+				`<img src="https://blog.example.com/posts/images/graph.png" alt="Connections over time"/>`,
+			},
+			notWant: []string{"Recent posts", "Great post", "Copyright", "analytics", "About", "Share"},
+		},
+		{
+			name:    "article split around an ad",
+			fixture: "testdata/articles/news.html",
+			url:     "https://news.example.com/local/bike-lanes",
+			want: []string{
+				"twelve kilometers of protected bike lanes",
+				"reviewed after the first year",
+			},
+			notWant: []string{"subscription", "Most read", "Sports"},
+		},
+		{
+			name:    "no article",
+			fixture: "testdata/articles/empty.html",
+			url:     "https://example.com/login",
+			wantErr: errNoArticle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := os.ReadFile(tt.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			article, err := extractArticle(page, tt.url)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(article, want) {
+					t.Errorf("expected article to contain %q, got:\n%s", want, article)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(article, notWant) {
+					t.Errorf("expected article not to contain %q, got:\n%s", notWant, article)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,1328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// fakeQuerier is an in-memory stand-in for the postgres backed
+// database.Queries. It mirrors the constraints of the schema closely
+// enough for the handlers to behave as they do against a real database.
+type fakeQuerier struct {
+	users   []database.User
+	feeds   []database.Feed
+	follows []database.FeedFollow
+	posts   []database.Post
+	websubs []database.WebsubSubscription
+	reads   []database.PostRead
+	stars   []database.PostStar
+	emails  []database.EmailDigest
+	rules   []database.FilterRule
+	tags    []database.Tag
+}
+
+var _ database.Querier = (*fakeQuerier)(nil)
+
+func newFakeQuerier() *fakeQuerier {
+	return &fakeQuerier{}
+}
+
+func (q *fakeQuerier) userByID(id uuid.UUID) (database.User, bool) {
+	for _, user := range q.users {
+		if user.ID == id {
+			return user, true
+		}
+	}
+	return database.User{}, false
+}
+
+func (q *fakeQuerier) feedByID(id uuid.UUID) (database.Feed, bool) {
+	for _, feed := range q.feeds {
+		if feed.ID == id {
+			return feed, true
+		}
+	}
+	return database.Feed{}, false
+}
+
+func (q *fakeQuerier) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	for _, feed := range q.feeds {
+		if feed.Url == arg.Url {
+			return database.Feed{}, errors.New("duplicate key value violates unique constraint \"feeds_url_key\"")
+		}
+	}
+	if _, ok := q.userByID(arg.UserID); !ok {
+		return database.Feed{}, errors.New("insert or update on table \"feeds\" violates foreign key constraint")
+	}
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	q.feeds = append(q.feeds, feed)
+	return feed, nil
+}
+
+func (q *fakeQuerier) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	for _, follow := range q.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			return database.CreateFeedFollowRow{}, errors.New("duplicate key value violates unique constraint")
+		}
+	}
+	user, ok := q.userByID(arg.UserID)
+	if !ok {
+		return database.CreateFeedFollowRow{}, errors.New("insert or update on table \"feed_follows\" violates foreign key constraint")
+	}
+	feed, ok := q.feedByID(arg.FeedID)
+	if !ok {
+		return database.CreateFeedFollowRow{}, errors.New("insert or update on table \"feed_follows\" violates foreign key constraint")
+	}
+	follow := database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	}
+	q.follows = append(q.follows, follow)
+	return database.CreateFeedFollowRow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		UpdatedAt: follow.UpdatedAt,
+		UserID:    follow.UserID,
+		FeedID:    follow.FeedID,
+		FeedName:  feed.Name,
+		UserName:  user.Name,
+	}, nil
+}
+
+func (q *fakeQuerier) CreatePost(ctx context.Context, arg database.CreatePostParams) (int64, error) {
+	for _, post := range q.posts {
+		if post.Url == arg.Url {
+			return 0, nil
+		}
+	}
+	q.posts = append(q.posts, database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+	})
+	return 1, nil
+}
+
+func (q *fakeQuerier) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	user := database.User{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+	}
+	q.users = append(q.users, user)
+	return user, nil
+}
+
+func (q *fakeQuerier) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	follows := q.follows[:0]
+	for _, follow := range q.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			q.rules = slices.DeleteFunc(q.rules, func(rule database.FilterRule) bool {
+				return rule.FeedFollowID.Valid && rule.FeedFollowID.UUID == follow.ID
+			})
+			q.tags = slices.DeleteFunc(q.tags, func(tag database.Tag) bool {
+				return tag.FeedFollowID == follow.ID
+			})
+			continue
+		}
+		follows = append(follows, follow)
+	}
+	q.follows = follows
+	return nil
+}
+
+func (q *fakeQuerier) DeleteUsers(ctx context.Context) error {
+	if len(q.feeds) > 0 {
+		return errors.New("update or delete on table \"users\" violates foreign key constraint \"feeds_user_id_fkey\" on table \"feeds\"")
+	}
+	q.users = nil
+	q.follows = nil
+	q.posts = nil
+	q.rules = nil
+	q.tags = nil
+	return nil
+}
+
+func (q *fakeQuerier) GetFeed(ctx context.Context, url string) (database.Feed, error) {
+	for _, feed := range q.feeds {
+		if feed.Url == url {
+			return feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) GetFeedsByName(ctx context.Context, name string) ([]database.Feed, error) {
+	var feeds []database.Feed
+	for _, feed := range q.feeds {
+		if feed.Name == name {
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds, nil
+}
+
+func (q *fakeQuerier) GetFeedsByNamePrefix(ctx context.Context, pattern string) ([]database.Feed, error) {
+	var feeds []database.Feed
+	for _, feed := range q.feeds {
+		if ilike(feed.Name, pattern) {
+			feeds = append(feeds, feed)
+		}
+	}
+	sort.SliceStable(feeds, func(i, j int) bool {
+		return feeds[i].Name < feeds[j].Name
+	})
+	return feeds, nil
+}
+
+func (q *fakeQuerier) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	var rows []database.GetFeedFollowsForUserRow
+	for _, follow := range q.follows {
+		if follow.UserID != userID {
+			continue
+		}
+		user, _ := q.userByID(follow.UserID)
+		feed, _ := q.feedByID(follow.FeedID)
+		rows = append(rows, database.GetFeedFollowsForUserRow{
+			ID:              follow.ID,
+			CreatedAt:       follow.CreatedAt,
+			UpdatedAt:       follow.UpdatedAt,
+			UserID:          follow.UserID,
+			FeedID:          follow.FeedID,
+			FeedName:        feed.Name,
+			FeedUrl:         feed.Url,
+			FeedTitle:       feed.Title,
+			FeedSiteUrl:     feed.SiteUrl,
+			FeedDescription: feed.Description,
+			FeedLanguage:    feed.Language,
+			FeedImageUrl:    feed.ImageUrl,
+			FeedGenerator:   feed.Generator,
+			UserName:        user.Name,
+		})
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+	var rows []database.GetFeedsRow
+	for _, feed := range q.feeds {
+		user, _ := q.userByID(feed.UserID)
+		rows = append(rows, database.GetFeedsRow{
+			ID:            feed.ID,
+			CreatedAt:     feed.CreatedAt,
+			UpdatedAt:     feed.UpdatedAt,
+			Name:          feed.Name,
+			Url:           feed.Url,
+			UserID:        feed.UserID,
+			LastFetchedAt: feed.LastFetchedAt,
+			HubUrl:        feed.HubUrl,
+			TopicUrl:      feed.TopicUrl,
+			Fulltext:      feed.Fulltext,
+			Title:         feed.Title,
+			Description:   feed.Description,
+			LastSuccessAt: feed.LastSuccessAt,
+			LastError:     feed.LastError,
+			SiteUrl:       feed.SiteUrl,
+			Language:      feed.Language,
+			ImageUrl:      feed.ImageUrl,
+			Generator:     feed.Generator,
+			UserName:      user.Name,
+		})
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	if len(q.feeds) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	next := q.feeds[0]
+	for _, feed := range q.feeds[1:] {
+		if !feed.LastFetchedAt.Valid {
+			if next.LastFetchedAt.Valid {
+				next = feed
+			}
+			continue
+		}
+		if next.LastFetchedAt.Valid && feed.LastFetchedAt.Time.Before(next.LastFetchedAt.Time) {
+			next = feed
+		}
+	}
+	return next, nil
+}
+
+func (q *fakeQuerier) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) ([]database.GetPostForUserRow, error) {
+	followed := map[uuid.UUID]uuid.UUID{}
+	for _, follow := range q.follows {
+		if follow.UserID == arg.UserID {
+			followed[follow.FeedID] = follow.ID
+		}
+	}
+	var rows []database.GetPostForUserRow
+	for _, post := range q.posts {
+		followID, ok := followed[post.FeedID]
+		if !ok || q.filtered(arg.UserID, followID, post) {
+			continue
+		}
+		if arg.Archived && !post.ArchivePath.Valid {
+			continue
+		}
+		readAt := q.readAt(arg.UserID, post.ID)
+		if !arg.IncludeRead && readAt.Valid {
+			continue
+		}
+		feed, _ := q.feedByID(post.FeedID)
+		if arg.FeedID.Valid && post.FeedID != arg.FeedID.UUID {
+			continue
+		}
+		if arg.Tag.Valid && !q.tagged(followID, arg.Tag.String) {
+			continue
+		}
+		position := postPosition(post.PublishedAt, post.CreatedAt)
+		if arg.Since.Valid && position.Before(arg.Since.Time) {
+			continue
+		}
+		if arg.Until.Valid && !position.Before(arg.Until.Time) {
+			continue
+		}
+		if arg.StoredSince.Valid && post.CreatedAt.Before(arg.StoredSince.Time) {
+			continue
+		}
+		if arg.Match.Valid && !ilike(post.Title.String, arg.Match.String) && !ilike(post.Description.String, arg.Match.String) && !ilike(post.Content.String, arg.Match.String) {
+			continue
+		}
+		if arg.BeforeID.Valid && !postBefore(post, arg.BeforePublishedAt.Time, arg.BeforeID.UUID) {
+			continue
+		}
+		if arg.AfterID.Valid && !postAfter(post, arg.AfterPublishedAt.Time, arg.AfterID.UUID) {
+			continue
+		}
+		rows = append(rows, database.GetPostForUserRow{
+			ID:          post.ID,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			Content:     post.Content,
+			ArchivePath: post.ArchivePath,
+			FeedName:    feed.Name,
+			ReadAt:      readAt,
+			StarredAt:   q.starredAt(arg.UserID, post.ID),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := postPosition(rows[i].PublishedAt, rows[i].CreatedAt), postPosition(rows[j].PublishedAt, rows[j].CreatedAt)
+		if !a.Equal(b) {
+			return a.After(b) != arg.AfterID.Valid
+		}
+		return (bytes.Compare(rows[i].ID[:], rows[j].ID[:]) > 0) != arg.AfterID.Valid
+	})
+	rows = rows[min(int(arg.Offset), len(rows)):]
+	if int(arg.Limit) < len(rows) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+// ilike matches s against a LIKE pattern like postgres' ILIKE does.
+func ilike(s, pattern string) bool {
+	var expr strings.Builder
+	expr.WriteString("(?is)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expr.WriteString(".*")
+		case r == '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(s)
+}
+
+func postPosition(publishedAt sql.NullTime, createdAt time.Time) time.Time {
+	if publishedAt.Valid {
+		return publishedAt.Time
+	}
+	return createdAt
+}
+
+func comparePost(post database.Post, publishedAt time.Time, id uuid.UUID) int {
+	if c := postPosition(post.PublishedAt, post.CreatedAt).Compare(publishedAt); c != 0 {
+		return c
+	}
+	return bytes.Compare(post.ID[:], id[:])
+}
+
+func postBefore(post database.Post, publishedAt time.Time, id uuid.UUID) bool {
+	return comparePost(post, publishedAt, id) < 0
+}
+
+func postAfter(post database.Post, publishedAt time.Time, id uuid.UUID) bool {
+	return comparePost(post, publishedAt, id) > 0
+}
+
+func (q *fakeQuerier) readAt(userID, postID uuid.UUID) sql.NullTime {
+	for _, read := range q.reads {
+		if read.UserID == userID && read.PostID == postID {
+			return sql.NullTime{Time: read.ReadAt, Valid: true}
+		}
+	}
+	return sql.NullTime{}
+}
+
+func (q *fakeQuerier) GetUser(ctx context.Context, name string) (database.User, error) {
+	for _, user := range q.users {
+		if user.Name == name {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, ok := q.userByID(id)
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (q *fakeQuerier) GetUsers(ctx context.Context) ([]database.User, error) {
+	users := append([]database.User(nil), q.users...)
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].CreatedAt.After(users[j].CreatedAt)
+	})
+	return users, nil
+}
+
+func (q *fakeQuerier) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].LastFetchedAt = arg.LastFetchedAt
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	feed, ok := q.feedByID(id)
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (q *fakeQuerier) SetFeedHub(ctx context.Context, arg database.SetFeedHubParams) error {
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].HubUrl = arg.HubUrl
+			q.feeds[i].TopicUrl = arg.TopicUrl
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) GetFeedsWithHub(ctx context.Context) ([]database.Feed, error) {
+	var feeds []database.Feed
+	for _, feed := range q.feeds {
+		if feed.HubUrl.Valid && feed.TopicUrl.Valid {
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds, nil
+}
+
+func (q *fakeQuerier) CreateWebSubSubscription(ctx context.Context, arg database.CreateWebSubSubscriptionParams) (database.WebsubSubscription, error) {
+	for i, sub := range q.websubs {
+		if sub.FeedID == arg.FeedID {
+			q.websubs[i].UpdatedAt = arg.UpdatedAt
+			q.websubs[i].HubUrl = arg.HubUrl
+			q.websubs[i].TopicUrl = arg.TopicUrl
+			q.websubs[i].CallbackUrl = arg.CallbackUrl
+			q.websubs[i].Secret = arg.Secret
+			return q.websubs[i], nil
+		}
+	}
+	sub := database.WebsubSubscription{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		FeedID:      arg.FeedID,
+		HubUrl:      arg.HubUrl,
+		TopicUrl:    arg.TopicUrl,
+		CallbackUrl: arg.CallbackUrl,
+		Secret:      arg.Secret,
+	}
+	q.websubs = append(q.websubs, sub)
+	return sub, nil
+}
+
+func (q *fakeQuerier) GetWebSubSubscription(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	for _, sub := range q.websubs {
+		if sub.FeedID == feedID {
+			return sub, nil
+		}
+	}
+	return database.WebsubSubscription{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) ConfirmWebSubSubscription(ctx context.Context, arg database.ConfirmWebSubSubscriptionParams) error {
+	for i := range q.websubs {
+		if q.websubs[i].FeedID == arg.FeedID {
+			q.websubs[i].LeaseExpiresAt = arg.LeaseExpiresAt
+			q.websubs[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) GetWebSubSubscriptionsToRenew(ctx context.Context, arg database.GetWebSubSubscriptionsToRenewParams) ([]database.WebsubSubscription, error) {
+	var subs []database.WebsubSubscription
+	for _, sub := range q.websubs {
+		if sub.LeaseExpiresAt.Valid && sub.LeaseExpiresAt.Time.Before(arg.LeaseExpiresBefore.Time) ||
+			!sub.LeaseExpiresAt.Valid && sub.UpdatedAt.Before(arg.UnverifiedBefore) {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+func (q *fakeQuerier) DeleteWebSubSubscription(ctx context.Context, feedID uuid.UUID) error {
+	subs := q.websubs[:0]
+	for _, sub := range q.websubs {
+		if sub.FeedID != feedID {
+			subs = append(subs, sub)
+		}
+	}
+	q.websubs = subs
+	return nil
+}
+
+func (q *fakeQuerier) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	for i := range q.posts {
+		if q.posts[i].Url == arg.Url {
+			q.posts[i].Content = arg.Content
+			q.posts[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) SetFeedFulltext(ctx context.Context, arg database.SetFeedFulltextParams) error {
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].Fulltext = arg.Fulltext
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) GetPostsToArchive(ctx context.Context, feedIds []uuid.UUID) ([]database.Post, error) {
+	var posts []database.Post
+	for _, post := range q.posts {
+		if slices.Contains(feedIds, post.FeedID) && !post.ArchivePath.Valid {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (q *fakeQuerier) SetPostArchivePath(ctx context.Context, arg database.SetPostArchivePathParams) error {
+	for i := range q.posts {
+		if q.posts[i].ID == arg.ID {
+			q.posts[i].ArchivePath = arg.ArchivePath
+			q.posts[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) GetPostByID(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	for _, post := range q.posts {
+		if post.ID == id {
+			return post, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) GetPostByURL(ctx context.Context, url string) (database.Post, error) {
+	for _, post := range q.posts {
+		if post.Url == url {
+			return post, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	if q.readAt(arg.UserID, arg.PostID).Valid {
+		return nil
+	}
+	q.reads = append(q.reads, database.PostRead{
+		UserID: arg.UserID,
+		PostID: arg.PostID,
+		ReadAt: arg.ReadAt,
+	})
+	return nil
+}
+
+func (q *fakeQuerier) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	reads := q.reads[:0]
+	for _, read := range q.reads {
+		if read.UserID == arg.UserID && read.PostID == arg.PostID {
+			continue
+		}
+		reads = append(reads, read)
+	}
+	q.reads = reads
+	return nil
+}
+
+func (q *fakeQuerier) MarkAllPostsRead(ctx context.Context, arg database.MarkAllPostsReadParams) (int64, error) {
+	var marked int64
+	for _, follow := range q.follows {
+		if follow.UserID != arg.UserID {
+			continue
+		}
+		if arg.FeedID.Valid && follow.FeedID != arg.FeedID.UUID {
+			continue
+		}
+		for _, post := range q.posts {
+			if post.FeedID != follow.FeedID {
+				continue
+			}
+			publishedAt := post.CreatedAt
+			if post.PublishedAt.Valid {
+				publishedAt = post.PublishedAt.Time
+			}
+			if arg.Before.Valid && !publishedAt.Before(arg.Before.Time) {
+				continue
+			}
+			if q.readAt(arg.UserID, post.ID).Valid {
+				continue
+			}
+			q.reads = append(q.reads, database.PostRead{
+				UserID: arg.UserID,
+				PostID: post.ID,
+				ReadAt: arg.ReadAt,
+			})
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (q *fakeQuerier) starredAt(userID, postID uuid.UUID) sql.NullTime {
+	for _, star := range q.stars {
+		if star.UserID == userID && star.PostID == postID {
+			return sql.NullTime{Time: star.StarredAt, Valid: true}
+		}
+	}
+	return sql.NullTime{}
+}
+
+func (q *fakeQuerier) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	if q.starredAt(arg.UserID, arg.PostID).Valid {
+		return nil
+	}
+	q.stars = append(q.stars, database.PostStar{
+		UserID:    arg.UserID,
+		PostID:    arg.PostID,
+		StarredAt: arg.StarredAt,
+	})
+	return nil
+}
+
+func (q *fakeQuerier) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	stars := q.stars[:0]
+	for _, star := range q.stars {
+		if star.UserID == arg.UserID && star.PostID == arg.PostID {
+			continue
+		}
+		stars = append(stars, star)
+	}
+	q.stars = stars
+	return nil
+}
+
+func (q *fakeQuerier) GetStarredPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetStarredPostsForUserRow, error) {
+	var rows []database.GetStarredPostsForUserRow
+	for _, star := range q.stars {
+		if star.UserID != userID {
+			continue
+		}
+		post, _ := q.GetPostByID(ctx, star.PostID)
+		feed, _ := q.feedByID(post.FeedID)
+		rows = append(rows, database.GetStarredPostsForUserRow{
+			ID:          post.ID,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			Content:     post.Content,
+			ArchivePath: post.ArchivePath,
+			FeedName:    feed.Name,
+			ReadAt:      q.readAt(userID, post.ID),
+			StarredAt:   star.StarredAt,
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].StarredAt.After(rows[j].StarredAt)
+	})
+	return rows, nil
+}
+
+func (q *fakeQuerier) GetStarredPostsToArchive(ctx context.Context, userID uuid.UUID) ([]database.Post, error) {
+	var posts []database.Post
+	for _, star := range q.stars {
+		if star.UserID != userID {
+			continue
+		}
+		post, _ := q.GetPostByID(ctx, star.PostID)
+		if !post.ArchivePath.Valid {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+// SearchPosts approximates postgres full-text search: a post matches if
+// its text contains every word of the query. Operators are ignored.
+func (q *fakeQuerier) SearchPosts(ctx context.Context, arg database.SearchPostsParams) ([]database.SearchPostsRow, error) {
+	words := strings.FieldsFunc(arg.Query, func(r rune) bool {
+		return strings.ContainsRune(" &|!()<->:*", r)
+	})
+	followed := map[uuid.UUID]bool{}
+	for _, follow := range q.follows {
+		if follow.UserID == arg.UserID {
+			followed[follow.FeedID] = true
+		}
+	}
+
+	var rows []database.SearchPostsRow
+	for _, post := range q.posts {
+		if !arg.AllFeeds && !followed[post.FeedID] {
+			continue
+		}
+		text := strings.ToLower(post.Title.String + " " + post.Description.String + " " + post.Content.String)
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(text, strings.ToLower(word)) {
+				matches = false
+			}
+		}
+		if !matches {
+			continue
+		}
+		feed, _ := q.feedByID(post.FeedID)
+		title := post.Title.String
+		for _, word := range words {
+			title = strings.ReplaceAll(title, word, "[["+word+"]]")
+		}
+		rows = append(rows, database.SearchPostsRow{
+			ID:          post.ID,
+			Url:         post.Url,
+			PublishedAt: post.PublishedAt,
+			FeedName:    feed.Name,
+			Title:       title,
+			Snippet:     post.Description.String,
+		})
+	}
+	if int(arg.Limit) < len(rows) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error) {
+	var rows []database.GetUnreadCountsForUserRow
+	for _, follow := range q.follows {
+		if follow.UserID != userID {
+			continue
+		}
+		feed, _ := q.feedByID(follow.FeedID)
+		row := database.GetUnreadCountsForUserRow{
+			ID:   feed.ID,
+			Name: feed.Name,
+			Url:  feed.Url,
+		}
+		for _, post := range q.posts {
+			if post.FeedID == feed.ID && !q.readAt(userID, post.ID).Valid && !q.filtered(userID, follow.ID, post) {
+				row.Unread++
+			}
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Name < rows[j].Name
+	})
+	return rows, nil
+}
+
+func (q *fakeQuerier) SetEmailDigest(ctx context.Context, arg database.SetEmailDigestParams) (database.EmailDigest, error) {
+	for i, settings := range q.emails {
+		if settings.UserID == arg.UserID {
+			q.emails[i].UpdatedAt = arg.UpdatedAt
+			q.emails[i].Email = arg.Email
+			q.emails[i].Schedule = arg.Schedule
+			q.emails[i].Format = arg.Format
+			return q.emails[i], nil
+		}
+	}
+	settings := database.EmailDigest{
+		UserID:    arg.UserID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Email:     arg.Email,
+		Schedule:  arg.Schedule,
+		Format:    arg.Format,
+	}
+	q.emails = append(q.emails, settings)
+	return settings, nil
+}
+
+func (q *fakeQuerier) GetEmailDigest(ctx context.Context, userID uuid.UUID) (database.EmailDigest, error) {
+	for _, settings := range q.emails {
+		if settings.UserID == userID {
+			return settings, nil
+		}
+	}
+	return database.EmailDigest{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) DeleteEmailDigest(ctx context.Context, userID uuid.UUID) error {
+	q.emails = slices.DeleteFunc(q.emails, func(settings database.EmailDigest) bool {
+		return settings.UserID == userID
+	})
+	return nil
+}
+
+func (q *fakeQuerier) GetEmailDigests(ctx context.Context) ([]database.GetEmailDigestsRow, error) {
+	var rows []database.GetEmailDigestsRow
+	for _, settings := range q.emails {
+		user, _ := q.userByID(settings.UserID)
+		rows = append(rows, database.GetEmailDigestsRow{
+			UserID:     settings.UserID,
+			CreatedAt:  settings.CreatedAt,
+			UpdatedAt:  settings.UpdatedAt,
+			Email:      settings.Email,
+			Schedule:   settings.Schedule,
+			Format:     settings.Format,
+			LastSentAt: settings.LastSentAt,
+			UserName:   user.Name,
+		})
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) MarkEmailDigestSent(ctx context.Context, arg database.MarkEmailDigestSentParams) error {
+	for i, settings := range q.emails {
+		if settings.UserID == arg.UserID {
+			q.emails[i].LastSentAt = arg.LastSentAt
+			q.emails[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+// filtered reports whether the filter rules of a user hide post of the
+// feed followed with followID.
+func (q *fakeQuerier) filtered(userID, followID uuid.UUID, post database.Post) bool {
+	for _, rule := range q.rules {
+		if rule.UserID != userID || (rule.FeedFollowID.Valid && rule.FeedFollowID.UUID != followID) {
+			continue
+		}
+		text := post.Title.String
+		if rule.Field == "text" {
+			text = strings.Join([]string{post.Title.String, post.Description.String, post.Content.String}, " ")
+		}
+		matched := regexp.MustCompile("(?i)" + rule.Pattern).MatchString(text)
+		if matched == (rule.Action == "hide") {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *fakeQuerier) CreateFilterRule(ctx context.Context, arg database.CreateFilterRuleParams) (database.FilterRule, error) {
+	rule := database.FilterRule{
+		ID:           arg.ID,
+		CreatedAt:    arg.CreatedAt,
+		UserID:       arg.UserID,
+		FeedFollowID: arg.FeedFollowID,
+		Action:       arg.Action,
+		Field:        arg.Field,
+		Pattern:      arg.Pattern,
+	}
+	q.rules = append(q.rules, rule)
+	return rule, nil
+}
+
+func (q *fakeQuerier) GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFilterRulesForUserRow, error) {
+	var rows []database.GetFilterRulesForUserRow
+	for _, rule := range q.rules {
+		if rule.UserID != userID {
+			continue
+		}
+		row := database.GetFilterRulesForUserRow{
+			ID:           rule.ID,
+			CreatedAt:    rule.CreatedAt,
+			UserID:       rule.UserID,
+			FeedFollowID: rule.FeedFollowID,
+			Action:       rule.Action,
+			Field:        rule.Field,
+			Pattern:      rule.Pattern,
+		}
+		for _, follow := range q.follows {
+			if rule.FeedFollowID.Valid && follow.ID == rule.FeedFollowID.UUID {
+				feed, _ := q.feedByID(follow.FeedID)
+				row.FeedName = sql.NullString{String: feed.Name, Valid: true}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) DeleteFilterRule(ctx context.Context, arg database.DeleteFilterRuleParams) (int64, error) {
+	n := len(q.rules)
+	q.rules = slices.DeleteFunc(q.rules, func(rule database.FilterRule) bool {
+		return rule.ID == arg.ID && rule.UserID == arg.UserID
+	})
+	return int64(n - len(q.rules)), nil
+}
+
+func (q *fakeQuerier) GetFeedFollow(ctx context.Context, arg database.GetFeedFollowParams) (database.FeedFollow, error) {
+	for _, follow := range q.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			return follow, nil
+		}
+	}
+	return database.FeedFollow{}, sql.ErrNoRows
+}
+
+// CheckRegexp accepts the patterns go and postgres both accept, it
+// rejects the go syntax postgres doesn't know.
+func (q *fakeQuerier) CheckRegexp(ctx context.Context, pattern string) error {
+	for _, goOnly := range []string{"(?P<", `\p`, `\P`} {
+		if strings.Contains(pattern, goOnly) {
+			return fmt.Errorf("pq: invalid regular expression: %s isn't supported", goOnly)
+		}
+	}
+	if i := strings.Index(pattern, "(?"); i > 0 {
+		return errors.New("pq: invalid regular expression: quantifier operand invalid")
+	}
+	_, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("pq: invalid regular expression: %w", err)
+	}
+	return nil
+}
+
+func (q *fakeQuerier) AddTag(ctx context.Context, arg database.AddTagParams) error {
+	for _, tag := range q.tags {
+		if tag.FeedFollowID == arg.FeedFollowID && tag.Name == arg.Name {
+			return nil
+		}
+	}
+	q.tags = append(q.tags, database.Tag{
+		FeedFollowID: arg.FeedFollowID,
+		Name:         arg.Name,
+		CreatedAt:    arg.CreatedAt,
+	})
+	return nil
+}
+
+func (q *fakeQuerier) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetTagsForUserRow, error) {
+	var rows []database.GetTagsForUserRow
+	for _, tag := range q.tags {
+		for _, follow := range q.follows {
+			if follow.ID == tag.FeedFollowID && follow.UserID == userID {
+				rows = append(rows, database.GetTagsForUserRow{
+					FeedFollowID: tag.FeedFollowID,
+					Name:         tag.Name,
+					CreatedAt:    tag.CreatedAt,
+					FeedID:       follow.FeedID,
+					FeedName:     q.feedName(follow.FeedID),
+				})
+			}
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Name != rows[j].Name {
+			return rows[i].Name < rows[j].Name
+		}
+		return rows[i].FeedName < rows[j].FeedName
+	})
+	return rows, nil
+}
+
+func (q *fakeQuerier) feedName(id uuid.UUID) string {
+	feed, _ := q.feedByID(id)
+	return feed.Name
+}
+
+func (q *fakeQuerier) DeleteTag(ctx context.Context, arg database.DeleteTagParams) (int64, error) {
+	n := len(q.tags)
+	q.tags = slices.DeleteFunc(q.tags, func(tag database.Tag) bool {
+		return tag.FeedFollowID == arg.FeedFollowID && tag.Name == arg.Name
+	})
+	return int64(n - len(q.tags)), nil
+}
+
+// tagged reports whether the follow has the tag or one nested in it.
+func (q *fakeQuerier) tagged(followID uuid.UUID, name string) bool {
+	for _, tag := range q.tags {
+		if tag.FeedFollowID == followID && (tag.Name == name || strings.HasPrefix(tag.Name, name+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *fakeQuerier) RenameFeed(ctx context.Context, arg database.RenameFeedParams) error {
+	for i, feed := range q.feeds {
+		if feed.ID == arg.ID {
+			q.feeds[i].Name = arg.Name
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) SetFeedURL(ctx context.Context, arg database.SetFeedURLParams) error {
+	for _, feed := range q.feeds {
+		if feed.Url == arg.Url && feed.ID != arg.ID {
+			return errors.New("duplicate key value violates unique constraint \"feeds_url_key\"")
+		}
+	}
+	for i, feed := range q.feeds {
+		if feed.ID == arg.ID {
+			q.feeds[i].Url = arg.Url
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+			q.feeds[i].HubUrl = sql.NullString{}
+			q.feeds[i].TopicUrl = sql.NullString{}
+			q.feeds[i].LastFetchedAt = sql.NullTime{}
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) GetFeedCounts(ctx context.Context, feedID uuid.UUID) (database.GetFeedCountsRow, error) {
+	var counts database.GetFeedCountsRow
+	for _, follow := range q.follows {
+		if follow.FeedID == feedID {
+			counts.Followers++
+		}
+	}
+	for _, post := range q.posts {
+		if post.FeedID == feedID {
+			counts.Posts++
+		}
+	}
+	return counts, nil
+}
+
+func (q *fakeQuerier) GetFeedStats(ctx context.Context, arg database.GetFeedStatsParams) (database.GetFeedStatsRow, error) {
+	counts, _ := q.GetFeedCounts(ctx, arg.FeedID)
+	stats := database.GetFeedStatsRow{Followers: counts.Followers, Posts: counts.Posts}
+	postAt := func(post database.Post) time.Time {
+		if post.PublishedAt.Valid {
+			return post.PublishedAt.Time
+		}
+		return post.CreatedAt
+	}
+	var first, last *database.Post
+	for i, post := range q.posts {
+		if post.FeedID != arg.FeedID {
+			continue
+		}
+		if !postAt(post).Before(arg.Since) {
+			stats.PostsSince++
+		}
+		if first == nil || postAt(post).Before(postAt(*first)) {
+			first = &q.posts[i]
+		}
+		if last == nil || !postAt(post).Before(postAt(*last)) {
+			last = &q.posts[i]
+		}
+	}
+	if first != nil {
+		stats.FirstPostPublishedAt = first.PublishedAt
+		stats.FirstPostCreatedAt = sql.NullTime{Time: first.CreatedAt, Valid: true}
+		stats.LastPostPublishedAt = last.PublishedAt
+		stats.LastPostCreatedAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+	}
+	return stats, nil
+}
+
+func (q *fakeQuerier) SearchFeeds(ctx context.Context, arg database.SearchFeedsParams) ([]database.SearchFeedsRow, error) {
+	var rows []database.SearchFeedsRow
+	for _, feed := range q.feeds {
+		if arg.Search.Valid && !ilike(feed.Name, arg.Search.String) && !ilike(feed.Url, arg.Search.String) && !ilike(feed.Description.String, arg.Search.String) {
+			continue
+		}
+		var followers int64
+		followed := false
+		for _, follow := range q.follows {
+			if follow.FeedID == feed.ID {
+				followers++
+				followed = followed || (arg.ExcludeUserID.Valid && follow.UserID == arg.ExcludeUserID.UUID)
+			}
+		}
+		if followed || followers < arg.MinFollowers {
+			continue
+		}
+		user, _ := q.userByID(feed.UserID)
+		rows = append(rows, database.SearchFeedsRow{
+			ID:            feed.ID,
+			CreatedAt:     feed.CreatedAt,
+			UpdatedAt:     feed.UpdatedAt,
+			Name:          feed.Name,
+			Url:           feed.Url,
+			UserID:        feed.UserID,
+			LastFetchedAt: feed.LastFetchedAt,
+			HubUrl:        feed.HubUrl,
+			TopicUrl:      feed.TopicUrl,
+			Fulltext:      feed.Fulltext,
+			Title:         feed.Title,
+			Description:   feed.Description,
+			LastSuccessAt: feed.LastSuccessAt,
+			LastError:     feed.LastError,
+			SiteUrl:       feed.SiteUrl,
+			Language:      feed.Language,
+			ImageUrl:      feed.ImageUrl,
+			Generator:     feed.Generator,
+			UserName:      user.Name,
+			Followers:     followers,
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].CreatedAt.Before(rows[j].CreatedAt)
+	})
+	sort.SliceStable(rows, func(i, j int) bool {
+		switch arg.Sort {
+		case "followers":
+			return rows[i].Followers > rows[j].Followers
+		case "recent":
+			return rows[i].CreatedAt.After(rows[j].CreatedAt)
+		case "name":
+			return strings.ToLower(rows[i].Name) < strings.ToLower(rows[j].Name)
+		}
+		return false
+	})
+	if arg.Limit.Valid && len(rows) > int(arg.Limit.Int32) {
+		rows = rows[:arg.Limit.Int32]
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) SetFeedFetchSuccess(ctx context.Context, arg database.SetFeedFetchSuccessParams) error {
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].Title = arg.Title
+			q.feeds[i].SiteUrl = arg.SiteUrl
+			q.feeds[i].Description = arg.Description
+			q.feeds[i].Language = arg.Language
+			q.feeds[i].ImageUrl = arg.ImageUrl
+			q.feeds[i].Generator = arg.Generator
+			q.feeds[i].LastSuccessAt = arg.LastSuccessAt
+			q.feeds[i].LastError = sql.NullString{}
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) SetFeedFetchError(ctx context.Context, arg database.SetFeedFetchErrorParams) error {
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].LastError = arg.LastError
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	for _, follow := range slices.Clone(q.follows) {
+		if follow.FeedID == id {
+			q.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{UserID: follow.UserID, FeedID: id})
+		}
+	}
+	for _, post := range q.posts {
+		if post.FeedID != id {
+			continue
+		}
+		q.reads = slices.DeleteFunc(q.reads, func(read database.PostRead) bool { return read.PostID == post.ID })
+		q.stars = slices.DeleteFunc(q.stars, func(star database.PostStar) bool { return star.PostID == post.ID })
+	}
+	q.posts = slices.DeleteFunc(q.posts, func(post database.Post) bool { return post.FeedID == id })
+	q.websubs = slices.DeleteFunc(q.websubs, func(sub database.WebsubSubscription) bool { return sub.FeedID == id })
+	q.feeds = slices.DeleteFunc(q.feeds, func(feed database.Feed) bool { return feed.ID == id })
+	return nil
+}
+
+func (q *fakeQuerier) DeleteFeeds(ctx context.Context) error {
+	for _, feed := range slices.Clone(q.feeds) {
+		q.DeleteFeed(ctx, feed.ID)
+	}
+	return nil
+}
+
+func (q *fakeQuerier) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	for _, feed := range q.feeds {
+		if feed.UserID == id {
+			return errors.New("update or delete on table \"users\" violates foreign key constraint \"feeds_user_id_fkey\" on table \"feeds\"")
+		}
+	}
+	for _, follow := range slices.Clone(q.follows) {
+		if follow.UserID == id {
+			q.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{UserID: id, FeedID: follow.FeedID})
+		}
+	}
+	q.reads = slices.DeleteFunc(q.reads, func(read database.PostRead) bool { return read.UserID == id })
+	q.stars = slices.DeleteFunc(q.stars, func(star database.PostStar) bool { return star.UserID == id })
+	q.emails = slices.DeleteFunc(q.emails, func(settings database.EmailDigest) bool { return settings.UserID == id })
+	q.rules = slices.DeleteFunc(q.rules, func(rule database.FilterRule) bool { return rule.UserID == id })
+	q.users = slices.DeleteFunc(q.users, func(user database.User) bool { return user.ID == id })
+	return nil
+}
+
+func (q *fakeQuerier) EnsureSystemUser(ctx context.Context, arg database.EnsureSystemUserParams) (database.User, error) {
+	if user, ok := q.userByID(arg.ID); ok {
+		return user, nil
+	}
+	return q.CreateUser(ctx, database.CreateUserParams(arg))
+}
+
+func (q *fakeQuerier) ChownFeed(ctx context.Context, arg database.ChownFeedParams) error {
+	for i, feed := range q.feeds {
+		if feed.ID == arg.ID {
+			q.feeds[i].UserID = arg.UserID
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) ReassignFeedsOfUser(ctx context.Context, arg database.ReassignFeedsOfUserParams) ([]database.ReassignFeedsOfUserRow, error) {
+	var rows []database.ReassignFeedsOfUserRow
+	for i, feed := range q.feeds {
+		if feed.UserID != arg.UserID {
+			continue
+		}
+		owner := arg.SystemUserID
+		var followedAt time.Time
+		for _, follow := range q.follows {
+			if follow.FeedID == feed.ID && follow.UserID != feed.UserID && (owner == arg.SystemUserID || follow.CreatedAt.Before(followedAt)) {
+				owner = follow.UserID
+				followedAt = follow.CreatedAt
+			}
+		}
+		q.feeds[i].UserID = owner
+		q.feeds[i].UpdatedAt = arg.UpdatedAt
+		user, _ := q.userByID(owner)
+		feed = q.feeds[i]
+		rows = append(rows, database.ReassignFeedsOfUserRow{
+			ID:            feed.ID,
+			CreatedAt:     feed.CreatedAt,
+			UpdatedAt:     feed.UpdatedAt,
+			Name:          feed.Name,
+			Url:           feed.Url,
+			UserID:        feed.UserID,
+			LastFetchedAt: feed.LastFetchedAt,
+			HubUrl:        feed.HubUrl,
+			TopicUrl:      feed.TopicUrl,
+			Fulltext:      feed.Fulltext,
+			Title:         feed.Title,
+			Description:   feed.Description,
+			LastSuccessAt: feed.LastSuccessAt,
+			LastError:     feed.LastError,
+			SiteUrl:       feed.SiteUrl,
+			Language:      feed.Language,
+			ImageUrl:      feed.ImageUrl,
+			Generator:     feed.Generator,
+			UserName:      user.Name,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Name < rows[j].Name
+	})
+	return rows, nil
+}
+
+func (q *fakeQuerier) MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) (int64, error) {
+	var moved int64
+	for i, follow := range q.follows {
+		if follow.FeedID != arg.FromFeedID {
+			continue
+		}
+		if slices.ContainsFunc(q.follows, func(other database.FeedFollow) bool {
+			return other.FeedID == arg.ToFeedID && other.UserID == follow.UserID
+		}) {
+			continue
+		}
+		q.follows[i].FeedID = arg.ToFeedID
+		q.follows[i].UpdatedAt = arg.UpdatedAt
+		moved++
+	}
+	return moved, nil
+}
+
+func (q *fakeQuerier) MovePosts(ctx context.Context, arg database.MovePostsParams) (int64, error) {
+	var moved int64
+	for i, post := range q.posts {
+		if post.FeedID == arg.FromFeedID {
+			q.posts[i].FeedID = arg.ToFeedID
+			q.posts[i].UpdatedAt = arg.UpdatedAt
+			moved++
+		}
+	}
+	return moved, nil
+}
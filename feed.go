@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const feedUsage = "usage: feed info <feed> | feed rename <feed> <name> | feed seturl <feed> <url> | feed chown <feed> <user> | feed rm <feed> [--yes]"
+
+// handlerFeed shows a feed or manages the feeds the user owns.
+func handlerFeed(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return errors.New(feedUsage)
+	}
+
+	switch cmd.args[0] {
+	case "info":
+		return feedInfo(s, cmd.args[1:])
+	case "rename":
+		return renameFeed(s, cmd.args[1:], user)
+	case "seturl":
+		return setFeedURL(s, cmd.args[1:], user)
+	case "chown":
+		return chownFeed(s, cmd.args[1:], user)
+	case "rm":
+		return removeFeed(s, cmd.args[1:], user)
+	}
+	return fmt.Errorf("unknown feed command %s, please use info, rename, seturl, chown or rm", cmd.args[0])
+}
+
+// ownedFeed resolves a feed that only its owner may change.
+func ownedFeed(s *state, user database.User, ref string) (database.Feed, error) {
+	feed, err := resolveFeed(s, ref)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	if feed.UserID != user.ID {
+		return database.Feed{}, fmt.Errorf("only the owner of %s can change it", feed.Name)
+	}
+	return feed, nil
+}
+
+// feedInfoWeeks is the number of recent weeks posts per week are
+// averaged over.
+const feedInfoWeeks = 4
+
+func feedInfo(s *state, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: feed info <feed>")
+	}
+	feed, err := resolveFeed(s, args[0])
+	if err != nil {
+		return err
+	}
+	owner, err := s.db.GetUserByID(context.Background(), feed.UserID)
+	if err != nil {
+		return fmt.Errorf("couldn't get the owner of %s: %w", feed.Name, err)
+	}
+	stats, err := s.db.GetFeedStats(context.Background(), database.GetFeedStatsParams{
+		FeedID: feed.ID,
+		Since:  time.Now().UTC().AddDate(0, 0, -7*feedInfoWeeks),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't get the stats of %s: %w", feed.Name, err)
+	}
+
+	fmt.Printf("%s (%s)\n", feed.Name, feed.Url)
+	if feed.Title.Valid {
+		fmt.Printf("title: %s\n", feed.Title.String)
+	}
+	if feed.Description.Valid {
+		fmt.Printf("description: %s\n", feed.Description.String)
+	}
+	if feed.SiteUrl.Valid {
+		fmt.Printf("site: %s\n", feed.SiteUrl.String)
+	}
+	if feed.Language.Valid {
+		fmt.Printf("language: %s\n", feed.Language.String)
+	}
+	if feed.ImageUrl.Valid {
+		fmt.Printf("image: %s\n", feed.ImageUrl.String)
+	}
+	if feed.Generator.Valid {
+		fmt.Printf("generator: %s\n", feed.Generator.String)
+	}
+	fmt.Printf("owner: %s\n", owner.Name)
+	fmt.Printf("followers: %d\n", stats.Followers)
+	fmt.Printf("posts: %d\n", stats.Posts)
+	fmt.Printf("posts per week: %.1f over the last %d weeks\n", float64(stats.PostsSince)/feedInfoWeeks, feedInfoWeeks)
+	if first := postDate(stats.FirstPostPublishedAt, stats.FirstPostCreatedAt); first.Valid {
+		fmt.Printf("first post: %s\n", formatInfoTime(first.Time))
+	}
+	if last := postDate(stats.LastPostPublishedAt, stats.LastPostCreatedAt); last.Valid {
+		fmt.Printf("last post: %s\n", formatInfoTime(last.Time))
+	}
+
+	switch {
+	case !feed.LastFetchedAt.Valid:
+		fmt.Println("last fetch: never")
+	case feed.LastError.Valid:
+		fmt.Printf("last fetch: %s, failed: %s\n", formatInfoTime(feed.LastFetchedAt.Time), feed.LastError.String)
+		if feed.LastSuccessAt.Valid {
+			fmt.Printf("last successful fetch: %s\n", formatInfoTime(feed.LastSuccessAt.Time))
+		} else {
+			fmt.Println("last successful fetch: never")
+		}
+	default:
+		fmt.Printf("last fetch: %s, ok\n", formatInfoTime(feed.LastFetchedAt.Time))
+	}
+	return nil
+}
+
+// postDate is the publication date of a post, or the time it was stored
+// if it has none. It isn't valid if there is no post.
+func postDate(publishedAt, createdAt sql.NullTime) sql.NullTime {
+	if publishedAt.Valid {
+		return publishedAt
+	}
+	return createdAt
+}
+
+func formatInfoTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func renameFeed(s *state, args []string, user database.User) error {
+	if len(args) != 2 || args[1] == "" {
+		return errors.New("usage: feed rename <feed> <name>")
+	}
+	feed, err := ownedFeed(s, user, args[0])
+	if err != nil {
+		return err
+	}
+	err = s.db.RenameFeed(context.Background(), database.RenameFeedParams{
+		Name:      args[1],
+		UpdatedAt: time.Now().UTC(),
+		ID:        feed.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't rename %s: %w", feed.Name, err)
+	}
+	fmt.Printf("renamed %s to %s\n", feed.Name, args[1])
+	return nil
+}
+
+func setFeedURL(s *state, args []string, user database.User) error {
+	if len(args) != 2 {
+		return errors.New("usage: feed seturl <feed> <url>")
+	}
+	feed, err := ownedFeed(s, user, args[0])
+	if err != nil {
+		return err
+	}
+	feedURL, err := canonicalURL(args[1])
+	if err != nil {
+		return err
+	}
+	if feedURL == feed.Url {
+		fmt.Printf("%s already has the url %s\n", feed.Name, feedURL)
+		return nil
+	}
+	if other, err := getFeedByURL(s, feedURL); err == nil && other.ID != feed.ID {
+		return fmt.Errorf("the feed %s already has the url %s", other.Name, other.Url)
+	}
+
+	err = changeFeedURL(s, feed.ID, feed.Name, feedURL)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("changed the url of %s from %s to %s\n", feed.Name, feed.Url, feedURL)
+	return nil
+}
+
+func changeFeedURL(s *state, id uuid.UUID, name, feedURL string) error {
+	err := s.db.SetFeedURL(context.Background(), database.SetFeedURLParams{
+		Url:       feedURL,
+		UpdatedAt: time.Now().UTC(),
+		ID:        id,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't change the url of %s: %w", name, err)
+	}
+	// the subscription is for the old url
+	err = s.db.DeleteWebSubSubscription(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("couldn't remove the websub subscription of %s: %w", name, err)
+	}
+	return nil
+}
+
+// chownFeed hands a feed over to another user. Feeds of the system user
+// can be taken over by anyone.
+func chownFeed(s *state, args []string, user database.User) error {
+	if len(args) != 2 {
+		return errors.New("usage: feed chown <feed> <user>")
+	}
+	feed, err := resolveFeed(s, args[0])
+	if err != nil {
+		return err
+	}
+	if feed.UserID != user.ID && feed.UserID != systemUserID {
+		return fmt.Errorf("only the owner of %s can change it", feed.Name)
+	}
+	owner, err := s.db.GetUser(context.Background(), args[1])
+	if err != nil {
+		return fmt.Errorf("couldn't find user %s", args[1])
+	}
+
+	err = s.db.ChownFeed(context.Background(), database.ChownFeedParams{
+		UserID:    owner.ID,
+		UpdatedAt: time.Now().UTC(),
+		ID:        feed.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't change the owner of %s: %w", feed.Name, err)
+	}
+	fmt.Printf("%s now belongs to %s\n", feed.Name, owner.Name)
+	return nil
+}
+
+func removeFeed(s *state, args []string, user database.User) error {
+	fs := newFlagSet("feed rm")
+	yes := fs.Bool("yes", false, "remove the feed with its follows and posts")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("usage: feed rm <feed> [--yes]")
+	}
+	feed, err := ownedFeed(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	counts, err := s.db.GetFeedCounts(context.Background(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't count the followers and posts of %s: %w", feed.Name, err)
+	}
+	if !*yes {
+		return fmt.Errorf("removing %s unfollows it for %d users and deletes its %d posts with their read and starred state, run feed rm %s --yes to remove it", feed.Name, counts.Followers, counts.Posts, args[0])
+	}
+
+	// follows, posts, tags, filter rules and subscriptions cascade
+	err = s.db.DeleteFeed(context.Background(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't remove %s: %w", feed.Name, err)
+	}
+	fmt.Printf("removed %s, unfollowed it for %d users and deleted %d posts\n", feed.Name, counts.Followers, counts.Posts)
+	return nil
+}
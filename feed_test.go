@@ -0,0 +1,201 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+)
+
+func TestHandlerFeed(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		bob := seedUser(q, "bob")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		seedFeed(q, alice, "news", "https://news.example.com/rss")
+		seedFeed(q, bob, "bobs feed", "https://bob.example.com/rss")
+		seedFollow(q, alice, blog)
+		seedFollow(q, bob, blog)
+		post := seedPost(q, blog, "post", "https://example.com/1", time.Now())
+		seedRead(q, bob, post)
+		seedStar(q, bob, post)
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerFeed), []handlerTest{
+		{
+			name:        "usage",
+			currentUser: "alice",
+			setup:       setup,
+			wantErr:     "usage: feed info",
+		},
+		{
+			name:        "rename",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"rename", "blog", "The Blog"},
+			wantOut:     []string{"renamed blog to The Blog"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if q.feeds[0].Name != "The Blog" {
+					t.Errorf("expected the feed to be renamed, got %s", q.feeds[0].Name)
+				}
+			},
+		},
+		{
+			name:        "rename feed of another user",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"rename", "bobs", "mine"},
+			wantErr:     "only the owner of bobs feed can change it",
+		},
+		{
+			name:        "seturl",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				setup(q)
+				q.feeds[0].HubUrl = sql.NullString{String: "https://hub.example.com", Valid: true}
+				q.feeds[0].LastFetchedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				q.websubs = append(q.websubs, database.WebsubSubscription{FeedID: q.feeds[0].ID})
+			},
+			args:    []string{"seturl", "blog", "https://example.com/feed.xml"},
+			wantOut: []string{"changed the url of blog from https://example.com/rss to https://example.com/feed.xml"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				feed := q.feeds[0]
+				if feed.Url != "https://example.com/feed.xml" || feed.HubUrl.Valid || feed.LastFetchedAt.Valid {
+					t.Errorf("unexpected feed %v", feed)
+				}
+				if len(q.websubs) != 0 {
+					t.Errorf("expected the websub subscription to be removed, got %v", q.websubs)
+				}
+			},
+		},
+		{
+			name:        "seturl to the url of another feed",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"seturl", "blog", "https://news.example.com/rss"},
+			wantErr:     "the feed news already has the url https://news.example.com/rss",
+		},
+		{
+			name:        "rm warns",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"rm", "blog"},
+			wantErr:     "removing blog unfollows it for 2 users and deletes its 1 posts with their read and starred state, run feed rm blog --yes to remove it",
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.feeds) != 3 {
+					t.Errorf("expected the feed to be kept, got %d feeds", len(q.feeds))
+				}
+			},
+		},
+		{
+			name:        "rm",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"rm", "blog", "--yes"},
+			wantOut:     []string{"removed blog, unfollowed it for 2 users and deleted 1 posts"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.feeds) != 2 || len(q.follows) != 0 || len(q.posts) != 0 || len(q.reads) != 0 || len(q.stars) != 0 {
+					t.Errorf("expected the feed to be removed with its follows and posts, got %d feeds, %d follows, %d posts", len(q.feeds), len(q.follows), len(q.posts))
+				}
+			},
+		},
+		{
+			name:        "rm feed of another user",
+			currentUser: "bob",
+			setup:       setup,
+			args:        []string{"rm", "blog", "--yes"},
+			wantErr:     "only the owner of blog can change it",
+		},
+	})
+}
+
+func TestHandlerFeedInfo(t *testing.T) {
+	first := time.Date(2020, 1, 2, 12, 0, 0, 0, time.Local)
+	last := time.Now().Add(-time.Hour).Local()
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		bob := seedUser(q, "bob")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		seedFeed(q, bob, "empty", "https://empty.example.com/rss")
+		seedFollow(q, alice, blog)
+		seedFollow(q, bob, blog)
+		seedPost(q, blog, "old", "https://example.com/1", first)
+		seedPost(q, blog, "recent", "https://example.com/2", time.Now().AddDate(0, 0, -3))
+		seedPost(q, blog, "new", "https://example.com/3", last)
+		q.feeds[0].Title = sql.NullString{String: "The Example Blog", Valid: true}
+		q.feeds[0].Description = sql.NullString{String: "all about examples", Valid: true}
+		q.feeds[0].SiteUrl = sql.NullString{String: "https://example.com/", Valid: true}
+		q.feeds[0].Language = sql.NullString{String: "en", Valid: true}
+		q.feeds[0].ImageUrl = sql.NullString{String: "https://example.com/favicon.ico", Valid: true}
+		q.feeds[0].Generator = sql.NullString{String: "Hugo", Valid: true}
+		q.feeds[0].LastFetchedAt = sql.NullTime{Time: last, Valid: true}
+		q.feeds[0].LastSuccessAt = sql.NullTime{Time: last, Valid: true}
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerFeed), []handlerTest{
+		{
+			name:        "usage",
+			currentUser: "bob",
+			setup:       setup,
+			args:        []string{"info"},
+			wantErr:     "usage: feed info <feed>",
+		},
+		{
+			name:        "info",
+			currentUser: "bob",
+			setup:       setup,
+			args:        []string{"info", "blog"},
+			wantOut: []string{
+				"blog (https://example.com/rss)",
+				"title: The Example Blog",
+				"description: all about examples",
+				"site: https://example.com/",
+				"language: en",
+				"image: https://example.com/favicon.ico",
+				"generator: Hugo",
+				"owner: alice",
+				"followers: 2",
+				"posts: 3",
+				"posts per week: 0.5 over the last 4 weeks",
+				"first post: 2020-01-02 12:00",
+				"last post: " + last.Format("2006-01-02 15:04"),
+				"last fetch: " + last.Format("2006-01-02 15:04") + ", ok",
+			},
+		},
+		{
+			name:        "failed fetch",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				setup(q)
+				q.feeds[0].LastFetchedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				q.feeds[0].LastError = sql.NullString{String: "unexpected status 500", Valid: true}
+			},
+			args: []string{"info", "blog"},
+			wantOut: []string{
+				", failed: unexpected status 500",
+				"last successful fetch: " + last.Format("2006-01-02 15:04"),
+			},
+		},
+		{
+			name:        "post without a publication date",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				setup(q)
+				seedPost(q, q.feeds[1], "undated", "https://empty.example.com/1", time.Time{})
+				q.posts[len(q.posts)-1].PublishedAt = sql.NullTime{}
+				q.posts[len(q.posts)-1].CreatedAt = first
+			},
+			args:    []string{"info", "empty"},
+			wantOut: []string{"posts: 1", "first post: 2020-01-02 12:00", "last post: 2020-01-02 12:00"},
+		},
+		{
+			name:        "never fetched",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"info", "empty"},
+			wantOut:     []string{"owner: bob", "followers: 0", "posts: 0", "last fetch: never"},
+			notOut:      []string{"title:", "site:", "image:", "first post:", "last post:"},
+		},
+	})
+}
@@ -2,21 +2,38 @@ package main
 
 import (
 	"context"
-	"net/http"
 	"encoding/xml"
-	"io"
 	"html"
+	"net/url"
+	"strings"
 )
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		// AtomLinks has to come before Link, otherwise <atom:link> elements
+		// end up in Link as well
+		AtomLinks []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		// the same goes for ITunesImage and Image
+		ITunesImage struct {
+			Href string `xml:"href,attr"`
+		} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"language"`
+		Generator   string `xml:"generator"`
+		Image       struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+		Item []RSSItem `xml:"item"`
 	} `xml:"channel"`
 }
 
+type AtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
 type RSSItem struct {
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
@@ -25,40 +42,84 @@ type RSSItem struct {
 }
 
 func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	client := &http.Client{}
-
-	feed := RSSFeed{}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	data, err := fetchURL(ctx, feedURL)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("User-Agent", "gator")
-
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
+	return parseFeed(data)
+}
 
-	data, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
+func parseFeed(data []byte) (*RSSFeed, error) {
+	feed := RSSFeed{}
 
-	err = xml.Unmarshal(data, &feed)
+	err := xml.Unmarshal(data, &feed)
 	if err != nil {
 		return nil, err
 	}
 
-	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
-	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
+	feed.Channel.Title = strings.TrimSpace(html.UnescapeString(feed.Channel.Title))
+	feed.Channel.Description = strings.TrimSpace(html.UnescapeString(feed.Channel.Description))
+	feed.Channel.Link = strings.TrimSpace(feed.Channel.Link)
+	feed.Channel.Language = strings.TrimSpace(feed.Channel.Language)
+	feed.Channel.Generator = strings.TrimSpace(feed.Channel.Generator)
 
+	// item descriptions are HTML, they are sanitized when they are stored
 	for i := 0; i < len(feed.Channel.Item); i++ {
 		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
-		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
 	}
 
 	return &feed, nil
-}
\ No newline at end of file
+}
+
+// hubLinks returns the WebSub hub and the topic (self) url the feed
+// advertises, empty strings if it doesn't advertise a hub.
+func (feed *RSSFeed) hubLinks() (hub string, topic string) {
+	for _, link := range feed.Channel.AtomLinks {
+		switch link.Rel {
+		case "hub":
+			if hub == "" {
+				hub = link.Href
+			}
+		case "self":
+			topic = link.Href
+		}
+	}
+	if hub == "" {
+		return "", ""
+	}
+	return hub, topic
+}
+
+// imageURL returns the url of the channel image, the podcast artwork if
+// there is none and the favicon of the site otherwise. Relative urls are
+// resolved against the site link, without a usable site link there is no
+// favicon and imageURL returns an empty string.
+func (feed *RSSFeed) imageURL() string {
+	site, err := url.Parse(feed.Channel.Link)
+	if err != nil || (site.Scheme != "http" && site.Scheme != "https") || site.Host == "" {
+		site = nil
+	}
+
+	for _, image := range []string{feed.Channel.Image.URL, feed.Channel.ITunesImage.Href} {
+		image = strings.TrimSpace(image)
+		if image == "" {
+			continue
+		}
+		u, err := url.Parse(image)
+		if err != nil {
+			continue
+		}
+		if site != nil {
+			u = site.ResolveReference(u)
+		}
+		if u.IsAbs() {
+			return u.String()
+		}
+	}
+
+	if site == nil {
+		return ""
+	}
+	return site.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+}
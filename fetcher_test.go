@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter(50 * time.Millisecond)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(ctx, "a.example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected requests to the same host to be spaced out, took %v", elapsed)
+	}
+
+	start = time.Now()
+	if err := limiter.wait(ctx, "b.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 25*time.Millisecond {
+		t.Errorf("expected other hosts not to wait, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := limiter.wait(ctx, "a.example.com"); err == nil {
+		t.Error("expected canceled wait to fail")
+	}
+}
+
+func TestFetchURLStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "gator" {
+			t.Errorf("unexpected user agent %q", r.Header.Get("User-Agent"))
+		}
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	useHostLimiter(t, 0)
+
+	body, err := fetchURL(context.Background(), server.URL+"/")
+	if err != nil || string(body) != "ok" {
+		t.Errorf("expected ok, got %q %v", body, err)
+	}
+	_, err = fetchURL(context.Background(), server.URL+"/missing")
+	if err == nil {
+		t.Error("expected error for missing page")
+	}
+}
+
+// useHostLimiter swaps the global rate limit for the duration of the test.
+func useHostLimiter(t *testing.T, interval time.Duration) {
+	t.Helper()
+	limits := hostLimits
+	hostLimits = newHostLimiter(interval)
+	t.Cleanup(func() {
+		hostLimits = limits
+	})
+}
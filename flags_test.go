@@ -0,0 +1,109 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantArgs   []string
+		wantFeeds  []string
+		wantToggle bool
+		wantErr    bool
+	}{
+		{
+			name:     "positional only",
+			args:     []string{"5"},
+			wantArgs: []string{"5"},
+		},
+		{
+			name:       "flags after positional",
+			args:       []string{"5", "--toggle", "--feed", "a", "-feed=b"},
+			wantArgs:   []string{"5"},
+			wantFeeds:  []string{"a", "b"},
+			wantToggle: true,
+		},
+		{
+			name:      "flags between positional",
+			args:      []string{"x", "--feed", "a", "y"},
+			wantArgs:  []string{"x", "y"},
+			wantFeeds: []string{"a"},
+		},
+		{
+			name:     "everything after -- is positional",
+			args:     []string{"x", "--", "--toggle", "y"},
+			wantArgs: []string{"x", "--toggle", "y"},
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"--nope"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var feeds stringList
+			fs := newFlagSet("test")
+			fs.Var(&feeds, "feed", "")
+			toggle := fs.Bool("toggle", false, "")
+
+			args, err := parseFlags(fs, tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("expected args %v, got %v", tt.wantArgs, args)
+			}
+			if !slices.Equal(feeds, tt.wantFeeds) {
+				t.Errorf("expected feeds %v, got %v", tt.wantFeeds, feeds)
+			}
+			if *toggle != tt.wantToggle {
+				t.Errorf("expected toggle %v, got %v", tt.wantToggle, *toggle)
+			}
+		})
+	}
+}
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "24h", want: now.Add(-24 * time.Hour)},
+		{value: "7d", want: now.AddDate(0, 0, -7)},
+		{value: "2026-10-01T08:30:00Z", want: time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC)},
+		{value: "2026-10-01", want: time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local).UTC()},
+		{value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTimeFlag(tt.value, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -11,6 +11,18 @@ const configFilename string = ".gatorconfig.json"
 type Config struct {
 	DbUrl 			string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+	ArchiveDir		string `json:"archive_dir,omitempty"`
+	SMTP			*SMTPConfig `json:"smtp,omitempty"`
+}
+
+// SMTPConfig is the mail server email digests are sent through.
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	// defaults to 587, the submission port
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from"`
 }
 
 
@@ -58,6 +70,19 @@ func write(cfg Config) error {
 	return nil
 }
 
+// GetArchiveDir returns the directory archived posts are stored in,
+// ~/.gator/archive unless archive_dir is set.
+func (cfg *Config) GetArchiveDir() (string, error) {
+	if cfg.ArchiveDir != "" {
+		return cfg.ArchiveDir, nil
+	}
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homePath, ".gator", "archive"), nil
+}
+
 func getConfigFilePath() (string, error) {
 	homePath, err := os.UserHomeDir()
 	if err != nil {
@@ -0,0 +1,160 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: email_digests.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteEmailDigest = `-- name: DeleteEmailDigest :exec
+DELETE FROM email_digests
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteEmailDigest(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteEmailDigest, userID)
+	return err
+}
+
+const getEmailDigest = `-- name: GetEmailDigest :one
+SELECT user_id, created_at, updated_at, email, schedule, format, last_sent_at
+FROM email_digests
+WHERE user_id = $1
+`
+
+func (q *Queries) GetEmailDigest(ctx context.Context, userID uuid.UUID) (EmailDigest, error) {
+	row := q.db.QueryRowContext(ctx, getEmailDigest, userID)
+	var i EmailDigest
+	err := row.Scan(
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Schedule,
+		&i.Format,
+		&i.LastSentAt,
+	)
+	return i, err
+}
+
+const getEmailDigests = `-- name: GetEmailDigests :many
+SELECT email_digests.user_id, email_digests.created_at, email_digests.updated_at, email_digests.email, email_digests.schedule, email_digests.format, email_digests.last_sent_at, users.name AS user_name
+FROM email_digests
+INNER JOIN users ON email_digests.user_id = users.id
+ORDER BY email_digests.created_at
+`
+
+type GetEmailDigestsRow struct {
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Email      string
+	Schedule   string
+	Format     string
+	LastSentAt sql.NullTime
+	UserName   string
+}
+
+func (q *Queries) GetEmailDigests(ctx context.Context) ([]GetEmailDigestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEmailDigests)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEmailDigestsRow
+	for rows.Next() {
+		var i GetEmailDigestsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.Schedule,
+			&i.Format,
+			&i.LastSentAt,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailDigestSent = `-- name: MarkEmailDigestSent :exec
+UPDATE email_digests
+SET last_sent_at = $1, updated_at = $2
+WHERE user_id = $3
+`
+
+type MarkEmailDigestSentParams struct {
+	LastSentAt sql.NullTime
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+}
+
+func (q *Queries) MarkEmailDigestSent(ctx context.Context, arg MarkEmailDigestSentParams) error {
+	_, err := q.db.ExecContext(ctx, markEmailDigestSent, arg.LastSentAt, arg.UpdatedAt, arg.UserID)
+	return err
+}
+
+const setEmailDigest = `-- name: SetEmailDigest :one
+INSERT INTO email_digests (user_id, created_at, updated_at, email, schedule, format)
+Values (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+) ON CONFLICT (user_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    email = EXCLUDED.email,
+    schedule = EXCLUDED.schedule,
+    format = EXCLUDED.format
+RETURNING user_id, created_at, updated_at, email, schedule, format, last_sent_at
+`
+
+type SetEmailDigestParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Email     string
+	Schedule  string
+	Format    string
+}
+
+func (q *Queries) SetEmailDigest(ctx context.Context, arg SetEmailDigestParams) (EmailDigest, error) {
+	row := q.db.QueryRowContext(ctx, setEmailDigest,
+		arg.UserID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Email,
+		arg.Schedule,
+		arg.Format,
+	)
+	var i EmailDigest
+	err := row.Scan(
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Schedule,
+		&i.Format,
+		&i.LastSentAt,
+	)
+	return i, err
+}
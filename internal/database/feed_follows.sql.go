@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -89,20 +90,67 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 	return err
 }
 
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id
+FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+	)
+	return i, err
+}
+
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 
-SELECT feeds.name AS feed_name, users.name AS user_name
+SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    feeds.title AS feed_title,
+    feeds.site_url AS feed_site_url,
+    feeds.description AS feed_description,
+    feeds.language AS feed_language,
+    feeds.image_url AS feed_image_url,
+    feeds.generator AS feed_generator,
+    users.name AS user_name
 FROM feed_follows
 INNER JOIN users
 ON feed_follows.user_id = users.id
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
 WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.created_at
 `
 
 type GetFeedFollowsForUserRow struct {
-	FeedName string
-	UserName string
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	UserID          uuid.UUID
+	FeedID          uuid.UUID
+	FeedName        string
+	FeedUrl         string
+	FeedTitle       sql.NullString
+	FeedSiteUrl     sql.NullString
+	FeedDescription sql.NullString
+	FeedLanguage    sql.NullString
+	FeedImageUrl    sql.NullString
+	FeedGenerator   sql.NullString
+	UserName        string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -114,7 +162,22 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.FeedName, &i.UserName); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedTitle,
+			&i.FeedSiteUrl,
+			&i.FeedDescription,
+			&i.FeedLanguage,
+			&i.FeedImageUrl,
+			&i.FeedGenerator,
+			&i.UserName,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -127,3 +190,29 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const moveFeedFollows = `-- name: MoveFeedFollows :execrows
+UPDATE feed_follows
+SET feed_id = $1, updated_at = $2
+WHERE feed_follows.feed_id = $3
+AND feed_follows.user_id NOT IN (
+    SELECT followers.user_id
+    FROM feed_follows AS followers
+    WHERE followers.feed_id = $1
+)
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+// Users following both feeds keep their follow of the feed moved to.
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -13,6 +13,23 @@ import (
 	"github.com/google/uuid"
 )
 
+const chownFeed = `-- name: ChownFeed :exec
+UPDATE feeds
+SET user_id = $1, updated_at = $2
+WHERE id = $3
+`
+
+type ChownFeedParams struct {
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) ChownFeed(ctx context.Context, arg ChownFeedParams) error {
+	_, err := q.db.ExecContext(ctx, chownFeed, arg.UserID, arg.UpdatedAt, arg.ID)
+	return err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 Values (
@@ -23,7 +40,7 @@ Values (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, topic_url, fulltext, title, description, last_success_at, last_error, site_url, language, image_url, generator
 `
 
 type CreateFeedParams struct {
@@ -53,12 +70,42 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Fulltext,
+		&i.Title,
+		&i.Description,
+		&i.LastSuccessAt,
+		&i.LastError,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const deleteFeeds = `-- name: DeleteFeeds :exec
+DELETE FROM feeds
+`
+
+func (q *Queries) DeleteFeeds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteFeeds)
+	return err
+}
+
 const getFeed = `-- name: GetFeed :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, topic_url, fulltext, title, description, last_success_at, last_error, site_url, language, image_url, generator
 FROM feeds
 WHERE url = $1
 `
@@ -74,21 +121,163 @@ func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Fulltext,
+		&i.Title,
+		&i.Description,
+		&i.LastSuccessAt,
+		&i.LastError,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+	)
+	return i, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, topic_url, fulltext, title, description, last_success_at, last_error, site_url, language, image_url, generator
+FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Fulltext,
+		&i.Title,
+		&i.Description,
+		&i.LastSuccessAt,
+		&i.LastError,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+	)
+	return i, err
+}
+
+const getFeedCounts = `-- name: GetFeedCounts :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS followers,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = $1) AS posts
+`
+
+type GetFeedCountsRow struct {
+	Followers int64
+	Posts     int64
+}
+
+func (q *Queries) GetFeedCounts(ctx context.Context, feedID uuid.UUID) (GetFeedCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedCounts, feedID)
+	var i GetFeedCountsRow
+	err := row.Scan(&i.Followers, &i.Posts)
+	return i, err
+}
+
+const getFeedStats = `-- name: GetFeedStats :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id) AS followers,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = feeds.id) AS posts,
+    (SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id = feeds.id
+        AND COALESCE(posts.published_at, posts.created_at) >= $1::timestamp) AS posts_since,
+    first_post.published_at AS first_post_published_at,
+    first_post.created_at AS first_post_created_at,
+    last_post.published_at AS last_post_published_at,
+    last_post.created_at AS last_post_created_at
+FROM feeds
+LEFT JOIN posts AS first_post
+ON first_post.id = (
+    SELECT posts.id
+    FROM posts
+    WHERE posts.feed_id = feeds.id
+    ORDER BY COALESCE(posts.published_at, posts.created_at), posts.id
+    LIMIT 1
+)
+LEFT JOIN posts AS last_post
+ON last_post.id = (
+    SELECT posts.id
+    FROM posts
+    WHERE posts.feed_id = feeds.id
+    ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id DESC
+    LIMIT 1
+)
+WHERE feeds.id = $2
+`
+
+type GetFeedStatsParams struct {
+	Since  time.Time
+	FeedID uuid.UUID
+}
+
+type GetFeedStatsRow struct {
+	Followers            int64
+	Posts                int64
+	PostsSince           int64
+	FirstPostPublishedAt sql.NullTime
+	FirstPostCreatedAt   sql.NullTime
+	LastPostPublishedAt  sql.NullTime
+	LastPostCreatedAt    sql.NullTime
+}
+
+// Posts without a publication date count from the time they were stored.
+// The first and last posts are joined as rows, so that their dates are
+// NULL if the feed has no posts.
+func (q *Queries) GetFeedStats(ctx context.Context, arg GetFeedStatsParams) (GetFeedStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedStats, arg.Since, arg.FeedID)
+	var i GetFeedStatsRow
+	err := row.Scan(
+		&i.Followers,
+		&i.Posts,
+		&i.PostsSince,
+		&i.FirstPostPublishedAt,
+		&i.FirstPostCreatedAt,
+		&i.LastPostPublishedAt,
+		&i.LastPostCreatedAt,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT feeds.name, feeds.url, users.name AS user_name
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.hub_url, feeds.topic_url, feeds.fulltext, feeds.title, feeds.description, feeds.last_success_at, feeds.last_error, feeds.site_url, feeds.language, feeds.image_url, feeds.generator, users.name AS user_name
 FROM feeds
 INNER JOIN users
 ON feeds.user_id = users.id
+ORDER BY feeds.created_at
 `
 
 type GetFeedsRow struct {
-	Name     string
-	Url      string
-	UserName string
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Name          string
+	Url           string
+	UserID        uuid.UUID
+	LastFetchedAt sql.NullTime
+	HubUrl        sql.NullString
+	TopicUrl      sql.NullString
+	Fulltext      bool
+	Title         sql.NullString
+	Description   sql.NullString
+	LastSuccessAt sql.NullTime
+	LastError     sql.NullString
+	SiteUrl       sql.NullString
+	Language      sql.NullString
+	ImageUrl      sql.NullString
+	Generator     sql.NullString
+	UserName      string
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -100,7 +289,173 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.UserName); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.Fulltext,
+			&i.Title,
+			&i.Description,
+			&i.LastSuccessAt,
+			&i.LastError,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsByName = `-- name: GetFeedsByName :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, topic_url, fulltext, title, description, last_success_at, last_error, site_url, language, image_url, generator
+FROM feeds
+WHERE name = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetFeedsByName(ctx context.Context, name string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.Fulltext,
+			&i.Title,
+			&i.Description,
+			&i.LastSuccessAt,
+			&i.LastError,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsByNamePrefix = `-- name: GetFeedsByNamePrefix :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, topic_url, fulltext, title, description, last_success_at, last_error, site_url, language, image_url, generator
+FROM feeds
+WHERE name ILIKE $1
+ORDER BY name, created_at
+`
+
+func (q *Queries) GetFeedsByNamePrefix(ctx context.Context, pattern string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByNamePrefix, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.Fulltext,
+			&i.Title,
+			&i.Description,
+			&i.LastSuccessAt,
+			&i.LastError,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsWithHub = `-- name: GetFeedsWithHub :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, topic_url, fulltext, title, description, last_success_at, last_error, site_url, language, image_url, generator
+FROM feeds
+WHERE hub_url IS NOT NULL AND topic_url IS NOT NULL
+`
+
+func (q *Queries) GetFeedsWithHub(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsWithHub)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.Fulltext,
+			&i.Title,
+			&i.Description,
+			&i.LastSuccessAt,
+			&i.LastError,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -115,7 +470,7 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, topic_url, fulltext, title, description, last_success_at, last_error, site_url, language, image_url, generator
 FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1
@@ -132,6 +487,17 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Fulltext,
+		&i.Title,
+		&i.Description,
+		&i.LastSuccessAt,
+		&i.LastError,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
 	)
 	return i, err
 }
@@ -152,3 +518,348 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.LastFetchedAt, arg.UpdatedAt, arg.ID)
 	return err
 }
+
+const reassignFeedsOfUser = `-- name: ReassignFeedsOfUser :many
+WITH reassigned_feeds AS (
+UPDATE feeds
+SET user_id = COALESCE((
+        SELECT feed_follows.user_id
+        FROM feed_follows
+        WHERE feed_follows.feed_id = feeds.id
+        AND feed_follows.user_id <> feeds.user_id
+        ORDER BY feed_follows.created_at
+        LIMIT 1
+    ), $1::uuid),
+    updated_at = $2
+WHERE feeds.user_id = $3
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, topic_url, fulltext, title, description, last_success_at, last_error, site_url, language, image_url, generator
+) SELECT
+    reassigned_feeds.id, reassigned_feeds.created_at, reassigned_feeds.updated_at, reassigned_feeds.name, reassigned_feeds.url, reassigned_feeds.user_id, reassigned_feeds.last_fetched_at, reassigned_feeds.hub_url, reassigned_feeds.topic_url, reassigned_feeds.fulltext, reassigned_feeds.title, reassigned_feeds.description, reassigned_feeds.last_success_at, reassigned_feeds.last_error, reassigned_feeds.site_url, reassigned_feeds.language, reassigned_feeds.image_url, reassigned_feeds.generator,
+    users.name AS user_name
+FROM reassigned_feeds
+INNER JOIN users
+ON reassigned_feeds.user_id = users.id
+ORDER BY reassigned_feeds.name
+`
+
+type ReassignFeedsOfUserParams struct {
+	SystemUserID uuid.UUID
+	UpdatedAt    time.Time
+	UserID       uuid.UUID
+}
+
+type ReassignFeedsOfUserRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Name          string
+	Url           string
+	UserID        uuid.UUID
+	LastFetchedAt sql.NullTime
+	HubUrl        sql.NullString
+	TopicUrl      sql.NullString
+	Fulltext      bool
+	Title         sql.NullString
+	Description   sql.NullString
+	LastSuccessAt sql.NullTime
+	LastError     sql.NullString
+	SiteUrl       sql.NullString
+	Language      sql.NullString
+	ImageUrl      sql.NullString
+	Generator     sql.NullString
+	UserName      string
+}
+
+// The feeds of a user go to the user who followed them first, feeds
+// nobody else follows to the system user.
+func (q *Queries) ReassignFeedsOfUser(ctx context.Context, arg ReassignFeedsOfUserParams) ([]ReassignFeedsOfUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, reassignFeedsOfUser, arg.SystemUserID, arg.UpdatedAt, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReassignFeedsOfUserRow
+	for rows.Next() {
+		var i ReassignFeedsOfUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.Fulltext,
+			&i.Title,
+			&i.Description,
+			&i.LastSuccessAt,
+			&i.LastError,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameFeed = `-- name: RenameFeed :exec
+UPDATE feeds
+SET name = $1, updated_at = $2
+WHERE id = $3
+`
+
+type RenameFeedParams struct {
+	Name      string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) error {
+	_, err := q.db.ExecContext(ctx, renameFeed, arg.Name, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const searchFeeds = `-- name: SearchFeeds :many
+WITH follower_counts AS (
+    SELECT feed_follows.feed_id, COUNT(*) AS followers
+    FROM feed_follows
+    GROUP BY feed_follows.feed_id
+)
+SELECT
+    feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.hub_url, feeds.topic_url, feeds.fulltext, feeds.title, feeds.description, feeds.last_success_at, feeds.last_error, feeds.site_url, feeds.language, feeds.image_url, feeds.generator,
+    users.name AS user_name,
+    COALESCE(follower_counts.followers, 0)::bigint AS followers
+FROM feeds
+INNER JOIN users
+ON feeds.user_id = users.id
+LEFT JOIN follower_counts
+ON follower_counts.feed_id = feeds.id
+WHERE ($1::text IS NULL
+    OR feeds.name ILIKE $1::text
+    OR feeds.url ILIKE $1::text
+    OR feeds.description ILIKE $1::text)
+AND ($2::uuid IS NULL OR NOT EXISTS (
+    SELECT 1
+    FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id = $2::uuid
+))
+AND COALESCE(follower_counts.followers, 0) >= $3::bigint
+ORDER BY
+    CASE WHEN $4::text = 'followers' THEN COALESCE(follower_counts.followers, 0) END DESC,
+    CASE WHEN $4::text = 'recent' THEN feeds.created_at END DESC,
+    CASE WHEN $4::text = 'name' THEN lower(feeds.name) END,
+    feeds.created_at
+LIMIT $5
+`
+
+type SearchFeedsParams struct {
+	Search        sql.NullString
+	ExcludeUserID uuid.NullUUID
+	MinFollowers  int64
+	Sort          string
+	Limit         sql.NullInt32
+}
+
+type SearchFeedsRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Name          string
+	Url           string
+	UserID        uuid.UUID
+	LastFetchedAt sql.NullTime
+	HubUrl        sql.NullString
+	TopicUrl      sql.NullString
+	Fulltext      bool
+	Title         sql.NullString
+	Description   sql.NullString
+	LastSuccessAt sql.NullTime
+	LastError     sql.NullString
+	SiteUrl       sql.NullString
+	Language      sql.NullString
+	ImageUrl      sql.NullString
+	Generator     sql.NullString
+	UserName      string
+	Followers     int64
+}
+
+// search is an ILIKE pattern matched against the name, url and channel
+// description. Feeds followed by exclude_user_id are left out, sort is
+// followers, recent (newest first) or name, feeds are ordered by
+// creation otherwise. A NULL limit returns all feeds.
+func (q *Queries) SearchFeeds(ctx context.Context, arg SearchFeedsParams) ([]SearchFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchFeeds,
+		arg.Search,
+		arg.ExcludeUserID,
+		arg.MinFollowers,
+		arg.Sort,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchFeedsRow
+	for rows.Next() {
+		var i SearchFeedsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.Fulltext,
+			&i.Title,
+			&i.Description,
+			&i.LastSuccessAt,
+			&i.LastError,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.UserName,
+			&i.Followers,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedFetchError = `-- name: SetFeedFetchError :exec
+UPDATE feeds
+SET last_error = $1, updated_at = $2
+WHERE id = $3
+`
+
+type SetFeedFetchErrorParams struct {
+	LastError sql.NullString
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetFeedFetchError(ctx context.Context, arg SetFeedFetchErrorParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchError, arg.LastError, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedFetchSuccess = `-- name: SetFeedFetchSuccess :exec
+UPDATE feeds
+SET title = $1,
+    site_url = $2,
+    description = $3,
+    language = $4,
+    image_url = $5,
+    generator = $6,
+    last_success_at = $7,
+    last_error = NULL,
+    updated_at = $8
+WHERE id = $9
+`
+
+type SetFeedFetchSuccessParams struct {
+	Title         sql.NullString
+	SiteUrl       sql.NullString
+	Description   sql.NullString
+	Language      sql.NullString
+	ImageUrl      sql.NullString
+	Generator     sql.NullString
+	LastSuccessAt sql.NullTime
+	UpdatedAt     time.Time
+	ID            uuid.UUID
+}
+
+// The channel metadata is replaced on every successful fetch, elements the
+// feed stopped sending are cleared.
+func (q *Queries) SetFeedFetchSuccess(ctx context.Context, arg SetFeedFetchSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchSuccess,
+		arg.Title,
+		arg.SiteUrl,
+		arg.Description,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+		arg.LastSuccessAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const setFeedFulltext = `-- name: SetFeedFulltext :exec
+UPDATE feeds
+SET fulltext = $1, updated_at = $2
+WHERE id = $3
+`
+
+type SetFeedFulltextParams struct {
+	Fulltext  bool
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetFeedFulltext(ctx context.Context, arg SetFeedFulltextParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFulltext, arg.Fulltext, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedHub = `-- name: SetFeedHub :exec
+UPDATE feeds
+SET hub_url = $1, topic_url = $2
+WHERE id = $3
+`
+
+type SetFeedHubParams struct {
+	HubUrl   sql.NullString
+	TopicUrl sql.NullString
+	ID       uuid.UUID
+}
+
+func (q *Queries) SetFeedHub(ctx context.Context, arg SetFeedHubParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedHub, arg.HubUrl, arg.TopicUrl, arg.ID)
+	return err
+}
+
+const setFeedURL = `-- name: SetFeedURL :exec
+UPDATE feeds
+SET url = $1, updated_at = $2, hub_url = NULL, topic_url = NULL, last_fetched_at = NULL
+WHERE id = $3
+`
+
+type SetFeedURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+// The hub of the old url doesn't apply to the new one, the feed is
+// fetched again as soon as possible to find its hub.
+func (q *Queries) SetFeedURL(ctx context.Context, arg SetFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedURL, arg.Url, arg.UpdatedAt, arg.ID)
+	return err
+}
@@ -0,0 +1,143 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: filter_rules.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const checkRegexp = `-- name: CheckRegexp :exec
+SELECT '' ~* $1::text
+`
+
+// Rules are matched by postgres, whose regular expressions aren't the ones
+// of Go, so patterns are checked by postgres before they are stored. It
+// fails if pattern isn't a valid regular expression.
+func (q *Queries) CheckRegexp(ctx context.Context, pattern string) error {
+	_, err := q.db.ExecContext(ctx, checkRegexp, pattern)
+	return err
+}
+
+const createFilterRule = `-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, created_at, user_id, feed_follow_id, action, field, pattern)
+Values (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING id, created_at, user_id, feed_follow_id, action, field, pattern
+`
+
+type CreateFilterRuleParams struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	FeedFollowID uuid.NullUUID
+	Action       string
+	Field        string
+	Pattern      string
+}
+
+func (q *Queries) CreateFilterRule(ctx context.Context, arg CreateFilterRuleParams) (FilterRule, error) {
+	row := q.db.QueryRowContext(ctx, createFilterRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.FeedFollowID,
+		arg.Action,
+		arg.Field,
+		arg.Pattern,
+	)
+	var i FilterRule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.FeedFollowID,
+		&i.Action,
+		&i.Field,
+		&i.Pattern,
+	)
+	return i, err
+}
+
+const deleteFilterRule = `-- name: DeleteFilterRule :execrows
+DELETE FROM filter_rules
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteFilterRuleParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteFilterRule(ctx context.Context, arg DeleteFilterRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFilterRule, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFilterRulesForUser = `-- name: GetFilterRulesForUser :many
+SELECT filter_rules.id, filter_rules.created_at, filter_rules.user_id, filter_rules.feed_follow_id, filter_rules.action, filter_rules.field, filter_rules.pattern, feeds.name AS feed_name
+FROM filter_rules
+LEFT JOIN feed_follows ON filter_rules.feed_follow_id = feed_follows.id
+LEFT JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE filter_rules.user_id = $1
+ORDER BY filter_rules.created_at
+`
+
+type GetFilterRulesForUserRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	FeedFollowID uuid.NullUUID
+	Action       string
+	Field        string
+	Pattern      string
+	FeedName     sql.NullString
+}
+
+func (q *Queries) GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetFilterRulesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFilterRulesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFilterRulesForUserRow
+	for rows.Next() {
+		var i GetFilterRulesForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.FeedFollowID,
+			&i.Action,
+			&i.Field,
+			&i.Pattern,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -11,6 +11,16 @@ import (
 	"github.com/google/uuid"
 )
 
+type EmailDigest struct {
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Email      string
+	Schedule   string
+	Format     string
+	LastSentAt sql.NullTime
+}
+
 type Feed struct {
 	ID            uuid.UUID
 	CreatedAt     time.Time
@@ -19,6 +29,17 @@ type Feed struct {
 	Url           string
 	UserID        uuid.UUID
 	LastFetchedAt sql.NullTime
+	HubUrl        sql.NullString
+	TopicUrl      sql.NullString
+	Fulltext      bool
+	Title         sql.NullString
+	Description   sql.NullString
+	LastSuccessAt sql.NullTime
+	LastError     sql.NullString
+	SiteUrl       sql.NullString
+	Language      sql.NullString
+	ImageUrl      sql.NullString
+	Generator     sql.NullString
 }
 
 type FeedFollow struct {
@@ -29,6 +50,16 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 }
 
+type FilterRule struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	FeedFollowID uuid.NullUUID
+	Action       string
+	Field        string
+	Pattern      string
+}
+
 type Post struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
@@ -38,6 +69,26 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	ArchivePath sql.NullString
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+type Tag struct {
+	FeedFollowID uuid.UUID
+	Name         string
+	CreatedAt    time.Time
 }
 
 type User struct {
@@ -46,3 +97,15 @@ type User struct {
 	UpdatedAt time.Time
 	Name      string
 }
+
+type WebsubSubscription struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FeedID         uuid.UUID
+	HubUrl         string
+	TopicUrl       string
+	CallbackUrl    string
+	Secret         string
+	LeaseExpiresAt sql.NullTime
+}
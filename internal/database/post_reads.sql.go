@@ -0,0 +1,141 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: post_reads.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getUnreadCountsForUser = `-- name: GetUnreadCountsForUser :many
+SELECT
+    feeds.id,
+    feeds.name,
+    feeds.url,
+    COUNT(posts.id) FILTER (WHERE post_reads.post_id IS NULL) AS unread
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN posts ON posts.feed_id = feeds.id
+    AND NOT EXISTS (
+        SELECT 1
+        FROM filter_rules
+        WHERE filter_rules.user_id = feed_follows.user_id
+        AND (filter_rules.feed_follow_id IS NULL OR filter_rules.feed_follow_id = feed_follows.id)
+        AND (CASE WHEN filter_rules.field = 'title'
+                THEN COALESCE(posts.title, '')
+                ELSE concat_ws(' ', posts.title, posts.description, posts.content)
+            END ~* filter_rules.pattern) = (filter_rules.action = 'hide')
+    )
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id
+ORDER BY feeds.name
+`
+
+type GetUnreadCountsForUserRow struct {
+	ID     uuid.UUID
+	Name   string
+	Url    string
+	Unread int64
+}
+
+// Posts hidden by a filter rule aren't counted, see GetPostForUser.
+func (q *Queries) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadCountsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsForUserRow
+	for rows.Next() {
+		var i GetUnreadCountsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.Unread,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllPostsRead = `-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, $1::timestamp
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $2
+AND ($3::uuid IS NULL OR posts.feed_id = $3)
+AND ($4::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < $4)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkAllPostsReadParams struct {
+	ReadAt time.Time
+	UserID uuid.UUID
+	FeedID uuid.NullUUID
+	Before sql.NullTime
+}
+
+func (q *Queries) MarkAllPostsRead(ctx context.Context, arg MarkAllPostsReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markAllPostsRead,
+		arg.ReadAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostRead = `-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+Values (
+    $1,
+    $2,
+    $3
+) ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkPostReadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID, arg.ReadAt)
+	return err
+}
+
+const markPostUnread = `-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2
+`
+
+type MarkPostUnreadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostUnread, arg.UserID, arg.PostID)
+	return err
+}
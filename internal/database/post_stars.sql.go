@@ -0,0 +1,159 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: post_stars.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getStarredPostsForUser = `-- name: GetStarredPostsForUser :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.archive_path,
+    feeds.name AS feed_name,
+    post_reads.read_at,
+    post_stars.starred_at
+FROM post_stars
+INNER JOIN posts ON post_stars.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = post_stars.user_id
+WHERE post_stars.user_id = $1
+ORDER BY post_stars.starred_at DESC
+`
+
+type GetStarredPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	ArchivePath sql.NullString
+	FeedName    string
+	ReadAt      sql.NullTime
+	StarredAt   time.Time
+}
+
+func (q *Queries) GetStarredPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetStarredPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStarredPostsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStarredPostsForUserRow
+	for rows.Next() {
+		var i GetStarredPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.ArchivePath,
+			&i.FeedName,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStarredPostsToArchive = `-- name: GetStarredPostsToArchive :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.archive_path
+FROM post_stars
+INNER JOIN posts ON post_stars.post_id = posts.id
+WHERE post_stars.user_id = $1
+AND posts.archive_path IS NULL
+ORDER BY post_stars.starred_at DESC
+`
+
+func (q *Queries) GetStarredPostsToArchive(ctx context.Context, userID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getStarredPostsToArchive, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.ArchivePath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const starPost = `-- name: StarPost :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+Values (
+    $1,
+    $2,
+    $3
+) ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type StarPostParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+func (q *Queries) StarPost(ctx context.Context, arg StarPostParams) error {
+	_, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.PostID, arg.StarredAt)
+	return err
+}
+
+const unstarPost = `-- name: UnstarPost :exec
+DELETE FROM post_stars
+WHERE user_id = $1 AND post_id = $2
+`
+
+type UnstarPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) UnstarPost(ctx context.Context, arg UnstarPostParams) error {
+	_, err := q.db.ExecContext(ctx, unstarPost, arg.UserID, arg.PostID)
+	return err
+}
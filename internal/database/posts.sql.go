@@ -11,9 +11,10 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createPost = `-- name: CreatePost :exec
+const createPost = `-- name: CreatePost :execrows
 INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
 Values (
     $1,
@@ -38,8 +39,8 @@ type CreatePostParams struct {
 	FeedID      uuid.UUID
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
-	_, err := q.db.ExecContext(ctx, createPost,
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createPost,
 		arg.ID,
 		arg.CreatedAt,
 		arg.UpdatedAt,
@@ -49,26 +50,217 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
 		arg.PublishedAt,
 		arg.FeedID,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, archive_path
+FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.ArchivePath,
+	)
+	return i, err
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, archive_path
+FROM posts
+WHERE url = $1
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.ArchivePath,
+	)
+	return i, err
 }
 
 const getPostForUser = `-- name: GetPostForUser :many
 SELECT 
-    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.archive_path,
+    feeds.name AS feed_name,
+    post_reads.read_at,
+    post_stars.starred_at
 FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars ON post_stars.post_id = posts.id AND post_stars.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-Limit $2
+AND (NOT $2::bool OR posts.archive_path IS NOT NULL)
+AND ($3::bool OR post_reads.post_id IS NULL)
+AND ($4::uuid IS NULL OR posts.feed_id = $4::uuid)
+AND ($5::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= $5::timestamp)
+AND ($6::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < $6::timestamp)
+AND ($7::timestamp IS NULL OR posts.created_at >= $7::timestamp)
+AND ($8::text IS NULL OR EXISTS (
+    SELECT 1
+    FROM tags
+    WHERE tags.feed_follow_id = feed_follows.id
+    AND (tags.name = $8::text OR starts_with(tags.name, $8::text || '/'))
+))
+AND ($9::text IS NULL
+    OR posts.title ILIKE $9::text
+    OR posts.description ILIKE $9::text
+    OR posts.content ILIKE $9::text)
+AND NOT EXISTS (
+    SELECT 1
+    FROM filter_rules
+    WHERE filter_rules.user_id = feed_follows.user_id
+    AND (filter_rules.feed_follow_id IS NULL OR filter_rules.feed_follow_id = feed_follows.id)
+    AND (CASE WHEN filter_rules.field = 'title'
+            THEN COALESCE(posts.title, '')
+            ELSE concat_ws(' ', posts.title, posts.description, posts.content)
+        END ~* filter_rules.pattern) = (filter_rules.action = 'hide')
+)
+AND ($10::uuid IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) < ($11::timestamp, $10::uuid))
+AND ($12::uuid IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) > ($13::timestamp, $12::uuid))
+ORDER BY
+    CASE WHEN $12::uuid IS NULL THEN COALESCE(posts.published_at, posts.created_at) END DESC,
+    CASE WHEN $12::uuid IS NULL THEN posts.id END DESC,
+    COALESCE(posts.published_at, posts.created_at) ASC,
+    posts.id ASC
+Limit $15
+OFFSET $14
 `
 
 type GetPostForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID            uuid.UUID
+	Archived          bool
+	IncludeRead       bool
+	FeedID            uuid.NullUUID
+	Since             sql.NullTime
+	Until             sql.NullTime
+	StoredSince       sql.NullTime
+	Tag               sql.NullString
+	Match             sql.NullString
+	BeforeID          uuid.NullUUID
+	BeforePublishedAt sql.NullTime
+	AfterID           uuid.NullUUID
+	AfterPublishedAt  sql.NullTime
+	Offset            int32
+	Limit             int32
+}
+
+type GetPostForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	ArchivePath sql.NullString
+	FeedName    string
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
 }
 
-func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostForUser, arg.UserID, arg.Limit)
+// Posts are ordered by (published_at, id), posts without a publication
+// date sort by the time they were stored. The before and after cursors
+// select the posts older or newer than a post, posts newer than the after
+// cursor are returned oldest first so the page ends next to the cursor.
+// match is an ILIKE pattern, tag also selects the feeds of its nested
+// tags like tag/subtag. Posts hidden by a filter rule of the user
+// are left out: hide rules drop the posts matching them, only rules the
+// posts not matching them. since and until apply to the publication
+// date, stored_since to the time the post was stored.
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) ([]GetPostForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostForUser,
+		arg.UserID,
+		arg.Archived,
+		arg.IncludeRead,
+		arg.FeedID,
+		arg.Since,
+		arg.Until,
+		arg.StoredSince,
+		arg.Tag,
+		arg.Match,
+		arg.BeforeID,
+		arg.BeforePublishedAt,
+		arg.AfterID,
+		arg.AfterPublishedAt,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostForUserRow
+	for rows.Next() {
+		var i GetPostForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.ArchivePath,
+			&i.FeedName,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsToArchive = `-- name: GetPostsToArchive :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, archive_path
+FROM posts
+WHERE feed_id = ANY($1::uuid[])
+AND archive_path IS NULL
+ORDER BY published_at DESC
+`
+
+func (q *Queries) GetPostsToArchive(ctx context.Context, feedIds []uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsToArchive, pq.Array(feedIds))
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +277,8 @@ func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams)
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.ArchivePath,
 		); err != nil {
 			return nil, err
 		}
@@ -98,3 +292,57 @@ func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams)
 	}
 	return items, nil
 }
+
+const movePosts = `-- name: MovePosts :execrows
+UPDATE posts
+SET feed_id = $1, updated_at = $2
+WHERE feed_id = $3
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setPostArchivePath = `-- name: SetPostArchivePath :exec
+UPDATE posts
+SET archive_path = $1, updated_at = $2
+WHERE id = $3
+`
+
+type SetPostArchivePathParams struct {
+	ArchivePath sql.NullString
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) SetPostArchivePath(ctx context.Context, arg SetPostArchivePathParams) error {
+	_, err := q.db.ExecContext(ctx, setPostArchivePath, arg.ArchivePath, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content = $1, updated_at = $2
+WHERE url = $3
+`
+
+type SetPostContentParams struct {
+	Content   sql.NullString
+	UpdatedAt time.Time
+	Url       string
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.Content, arg.UpdatedAt, arg.Url)
+	return err
+}
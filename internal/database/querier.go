@@ -0,0 +1,118 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type Querier interface {
+	AddTag(ctx context.Context, arg AddTagParams) error
+	// Rules are matched by postgres, whose regular expressions aren't the ones
+	// of Go, so patterns are checked by postgres before they are stored. It
+	// fails if pattern isn't a valid regular expression.
+	CheckRegexp(ctx context.Context, pattern string) error
+	ChownFeed(ctx context.Context, arg ChownFeedParams) error
+	ConfirmWebSubSubscription(ctx context.Context, arg ConfirmWebSubSubscriptionParams) error
+	CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error)
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	CreateFilterRule(ctx context.Context, arg CreateFilterRuleParams) (FilterRule, error)
+	CreatePost(ctx context.Context, arg CreatePostParams) (int64, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWebSubSubscription(ctx context.Context, arg CreateWebSubSubscriptionParams) (WebsubSubscription, error)
+	DeleteEmailDigest(ctx context.Context, userID uuid.UUID) error
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) error
+	DeleteFeeds(ctx context.Context) error
+	DeleteFilterRule(ctx context.Context, arg DeleteFilterRuleParams) (int64, error)
+	DeleteTag(ctx context.Context, arg DeleteTagParams) (int64, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteUsers(ctx context.Context) error
+	DeleteWebSubSubscription(ctx context.Context, feedID uuid.UUID) error
+	// The system user owns the feeds nobody else can take over.
+	EnsureSystemUser(ctx context.Context, arg EnsureSystemUserParams) (User, error)
+	GetEmailDigest(ctx context.Context, userID uuid.UUID) (EmailDigest, error)
+	GetEmailDigests(ctx context.Context) ([]GetEmailDigestsRow, error)
+	GetFeed(ctx context.Context, url string) (Feed, error)
+	GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error)
+	GetFeedCounts(ctx context.Context, feedID uuid.UUID) (GetFeedCountsRow, error)
+	GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error)
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error)
+	// Posts without a publication date count from the time they were stored.
+	// The first and last posts are joined as rows, so that their dates are
+	// NULL if the feed has no posts.
+	GetFeedStats(ctx context.Context, arg GetFeedStatsParams) (GetFeedStatsRow, error)
+	GetFeeds(ctx context.Context) ([]GetFeedsRow, error)
+	GetFeedsByName(ctx context.Context, name string) ([]Feed, error)
+	GetFeedsByNamePrefix(ctx context.Context, pattern string) ([]Feed, error)
+	GetFeedsWithHub(ctx context.Context) ([]Feed, error)
+	GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetFilterRulesForUserRow, error)
+	GetNextFeedToFetch(ctx context.Context) (Feed, error)
+	GetPostByID(ctx context.Context, id uuid.UUID) (Post, error)
+	GetPostByURL(ctx context.Context, url string) (Post, error)
+	// Posts are ordered by (published_at, id), posts without a publication
+	// date sort by the time they were stored. The before and after cursors
+	// select the posts older or newer than a post, posts newer than the after
+	// cursor are returned oldest first so the page ends next to the cursor.
+	// match is an ILIKE pattern, tag also selects the feeds of its nested
+	// tags like tag/subtag. Posts hidden by a filter rule of the user
+	// are left out: hide rules drop the posts matching them, only rules the
+	// posts not matching them. since and until apply to the publication
+	// date, stored_since to the time the post was stored.
+	GetPostForUser(ctx context.Context, arg GetPostForUserParams) ([]GetPostForUserRow, error)
+	GetPostsToArchive(ctx context.Context, feedIds []uuid.UUID) ([]Post, error)
+	GetStarredPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetStarredPostsForUserRow, error)
+	GetStarredPostsToArchive(ctx context.Context, userID uuid.UUID) ([]Post, error)
+	GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetTagsForUserRow, error)
+	// Posts hidden by a filter rule aren't counted, see GetPostForUser.
+	GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsForUserRow, error)
+	GetUser(ctx context.Context, name string) (User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	GetWebSubSubscription(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error)
+	// Subscriptions the hub never verified are requested again once they were
+	// last requested before unverified_before.
+	GetWebSubSubscriptionsToRenew(ctx context.Context, arg GetWebSubSubscriptionsToRenewParams) ([]WebsubSubscription, error)
+	MarkAllPostsRead(ctx context.Context, arg MarkAllPostsReadParams) (int64, error)
+	MarkEmailDigestSent(ctx context.Context, arg MarkEmailDigestSentParams) error
+	MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error
+	MarkPostRead(ctx context.Context, arg MarkPostReadParams) error
+	MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) error
+	// Users following both feeds keep their follow of the feed moved to.
+	MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) (int64, error)
+	MovePosts(ctx context.Context, arg MovePostsParams) (int64, error)
+	// The feeds of a user go to the user who followed them first, feeds
+	// nobody else follows to the system user.
+	ReassignFeedsOfUser(ctx context.Context, arg ReassignFeedsOfUserParams) ([]ReassignFeedsOfUserRow, error)
+	RenameFeed(ctx context.Context, arg RenameFeedParams) error
+	// search is an ILIKE pattern matched against the name, url and channel
+	// description. Feeds followed by exclude_user_id are left out, sort is
+	// followers, recent (newest first) or name, feeds are ordered by
+	// creation otherwise. A NULL limit returns all feeds.
+	SearchFeeds(ctx context.Context, arg SearchFeedsParams) ([]SearchFeedsRow, error)
+	// query is in to_tsquery syntax. Matches are highlighted with [[ and ]]
+	// in the headline of the title and the snippet of the body. The search
+	// vector has to be the expression of posts_search_idx for the index to be
+	// used.
+	SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error)
+	SetEmailDigest(ctx context.Context, arg SetEmailDigestParams) (EmailDigest, error)
+	SetFeedFetchError(ctx context.Context, arg SetFeedFetchErrorParams) error
+	// The channel metadata is replaced on every successful fetch, elements the
+	// feed stopped sending are cleared.
+	SetFeedFetchSuccess(ctx context.Context, arg SetFeedFetchSuccessParams) error
+	SetFeedFulltext(ctx context.Context, arg SetFeedFulltextParams) error
+	SetFeedHub(ctx context.Context, arg SetFeedHubParams) error
+	// The hub of the old url doesn't apply to the new one, the feed is
+	// fetched again as soon as possible to find its hub.
+	SetFeedURL(ctx context.Context, arg SetFeedURLParams) error
+	SetPostArchivePath(ctx context.Context, arg SetPostArchivePathParams) error
+	SetPostContent(ctx context.Context, arg SetPostContentParams) error
+	StarPost(ctx context.Context, arg StarPostParams) error
+	UnstarPost(ctx context.Context, arg UnstarPostParams) error
+}
+
+var _ Querier = (*Queries)(nil)
@@ -0,0 +1,97 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: search.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+    posts.id,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_rank((setweight(to_tsvector('english', COALESCE(posts.title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(posts.description, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(posts.content, '')), 'C')),
+        to_tsquery('english', $1::text)) AS rank,
+    ts_headline('english', COALESCE(posts.title, ''), to_tsquery('english', $1::text),
+        'HighlightAll=true, StartSel=[[, StopSel=]]')::text AS title,
+    ts_headline('english', COALESCE(posts.content, posts.description, ''), to_tsquery('english', $1::text),
+        'MaxFragments=2, MinWords=5, MaxWords=20, StartSel=[[, StopSel=]]')::text AS snippet
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE (setweight(to_tsvector('english', COALESCE(posts.title, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(posts.description, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(posts.content, '')), 'C'))
+    @@ to_tsquery('english', $1::text)
+AND ($2::bool OR posts.feed_id IN (
+    SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = $3
+))
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST, posts.id
+LIMIT $4
+`
+
+type SearchPostsParams struct {
+	Query    string
+	AllFeeds bool
+	UserID   uuid.UUID
+	Limit    int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Url         string
+	PublishedAt sql.NullTime
+	FeedName    string
+	Rank        float32
+	Title       string
+	Snippet     string
+}
+
+// query is in to_tsquery syntax. Matches are highlighted with [[ and ]]
+// in the headline of the title and the snippet of the body. The search
+// vector has to be the expression of posts_search_idx for the index to be
+// used.
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.AllFeeds,
+		arg.UserID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
+			&i.Title,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,97 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: tags.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const addTag = `-- name: AddTag :exec
+INSERT INTO tags (feed_follow_id, name, created_at)
+Values (
+    $1,
+    $2,
+    $3
+) ON CONFLICT (feed_follow_id, name) DO NOTHING
+`
+
+type AddTagParams struct {
+	FeedFollowID uuid.UUID
+	Name         string
+	CreatedAt    time.Time
+}
+
+func (q *Queries) AddTag(ctx context.Context, arg AddTagParams) error {
+	_, err := q.db.ExecContext(ctx, addTag, arg.FeedFollowID, arg.Name, arg.CreatedAt)
+	return err
+}
+
+const deleteTag = `-- name: DeleteTag :execrows
+DELETE FROM tags
+WHERE feed_follow_id = $1 AND name = $2
+`
+
+type DeleteTagParams struct {
+	FeedFollowID uuid.UUID
+	Name         string
+}
+
+func (q *Queries) DeleteTag(ctx context.Context, arg DeleteTagParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteTag, arg.FeedFollowID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getTagsForUser = `-- name: GetTagsForUser :many
+SELECT tags.feed_follow_id, tags.name, tags.created_at, feed_follows.feed_id, feeds.name AS feed_name
+FROM tags
+INNER JOIN feed_follows ON tags.feed_follow_id = feed_follows.id
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY tags.name, feeds.name
+`
+
+type GetTagsForUserRow struct {
+	FeedFollowID uuid.UUID
+	Name         string
+	CreatedAt    time.Time
+	FeedID       uuid.UUID
+	FeedName     string
+}
+
+func (q *Queries) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetTagsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTagsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagsForUserRow
+	for rows.Next() {
+		var i GetTagsForUserRow
+		if err := rows.Scan(
+			&i.FeedFollowID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.FeedID,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,169 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: websub.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const confirmWebSubSubscription = `-- name: ConfirmWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET lease_expires_at = $1, updated_at = $2
+WHERE feed_id = $3
+`
+
+type ConfirmWebSubSubscriptionParams struct {
+	LeaseExpiresAt sql.NullTime
+	UpdatedAt      time.Time
+	FeedID         uuid.UUID
+}
+
+func (q *Queries) ConfirmWebSubSubscription(ctx context.Context, arg ConfirmWebSubSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, confirmWebSubSubscription, arg.LeaseExpiresAt, arg.UpdatedAt, arg.FeedID)
+	return err
+}
+
+const createWebSubSubscription = `-- name: CreateWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, callback_url, secret)
+Values (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+) ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    callback_url = EXCLUDED.callback_url,
+    secret = EXCLUDED.secret
+RETURNING id, created_at, updated_at, feed_id, hub_url, topic_url, callback_url, secret, lease_expires_at
+`
+
+type CreateWebSubSubscriptionParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	FeedID      uuid.UUID
+	HubUrl      string
+	TopicUrl    string
+	CallbackUrl string
+	Secret      string
+}
+
+func (q *Queries) CreateWebSubSubscription(ctx context.Context, arg CreateWebSubSubscriptionParams) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, createWebSubSubscription,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.HubUrl,
+		arg.TopicUrl,
+		arg.CallbackUrl,
+		arg.Secret,
+	)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.CallbackUrl,
+		&i.Secret,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const deleteWebSubSubscription = `-- name: DeleteWebSubSubscription :exec
+DELETE FROM websub_subscriptions
+WHERE feed_id = $1
+`
+
+func (q *Queries) DeleteWebSubSubscription(ctx context.Context, feedID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteWebSubSubscription, feedID)
+	return err
+}
+
+const getWebSubSubscription = `-- name: GetWebSubSubscription :one
+SELECT id, created_at, updated_at, feed_id, hub_url, topic_url, callback_url, secret, lease_expires_at
+FROM websub_subscriptions
+WHERE feed_id = $1
+`
+
+func (q *Queries) GetWebSubSubscription(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscription, feedID)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.CallbackUrl,
+		&i.Secret,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const getWebSubSubscriptionsToRenew = `-- name: GetWebSubSubscriptionsToRenew :many
+SELECT id, created_at, updated_at, feed_id, hub_url, topic_url, callback_url, secret, lease_expires_at
+FROM websub_subscriptions
+WHERE lease_expires_at < $1
+OR (lease_expires_at IS NULL AND updated_at < $2)
+ORDER BY lease_expires_at ASC NULLS FIRST
+`
+
+type GetWebSubSubscriptionsToRenewParams struct {
+	LeaseExpiresBefore sql.NullTime
+	UnverifiedBefore   time.Time
+}
+
+// Subscriptions the hub never verified are requested again once they were
+// last requested before unverified_before.
+func (q *Queries) GetWebSubSubscriptionsToRenew(ctx context.Context, arg GetWebSubSubscriptionsToRenewParams) ([]WebsubSubscription, error) {
+	rows, err := q.db.QueryContext(ctx, getWebSubSubscriptionsToRenew, arg.LeaseExpiresBefore, arg.UnverifiedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebsubSubscription
+	for rows.Next() {
+		var i WebsubSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.CallbackUrl,
+			&i.Secret,
+			&i.LeaseExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arglp/gator/internal/config"
+	"github.com/arglp/gator/internal/database"
+)
+
+const (
+	defaultSMTPPort = 587
+	smtpTimeout     = 30 * time.Second
+)
+
+// mailer sends email through the SMTP server of the config. The
+// connection is upgraded with STARTTLS, only servers on localhost may
+// be used without it.
+type mailer struct {
+	cfg config.SMTPConfig
+	// used for STARTTLS, the server name is set from the config
+	tlsConfig *tls.Config
+}
+
+func newMailer(cfg *config.Config) (*mailer, error) {
+	if cfg.SMTP == nil || cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+		return nil, errors.New("please configure the smtp host and from address in ~/.gatorconfig.json")
+	}
+	return &mailer{cfg: *cfg.SMTP}, nil
+}
+
+func handlerMailer(s *state, cmd command) error {
+	fs := newFlagSet(cmd.name)
+	interval := fs.Duration("interval", 5*time.Minute, "time between checks for due digests")
+	once := fs.Bool("once", false, "send the due digests and exit")
+	_, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	m, err := newMailer(s.cfg)
+	if err != nil {
+		return err
+	}
+
+	if *once {
+		return sendDueDigests(s, m, time.Now().UTC())
+	}
+
+	log.Printf("sending email digests, checking every %v", *interval)
+	ticker := time.NewTicker(*interval)
+	for ; ; <-ticker.C {
+		err = sendDueDigests(s, m, time.Now().UTC())
+		if err != nil {
+			log.Printf("couldn't send digests: %v", err)
+		}
+	}
+}
+
+// sendDueDigests emails the digests that are due at now. A digest covers
+// the posts stored since the last one, or one schedule period for the first.
+func sendDueDigests(s *state, m *mailer, now time.Time) error {
+	digests, err := s.db.GetEmailDigests(context.Background())
+	if err != nil {
+		return fmt.Errorf("couldn't get email digests: %w", err)
+	}
+
+	for _, settings := range digests {
+		every, err := parseSchedule(settings.Schedule)
+		if err != nil {
+			log.Printf("skipping digest of %s: %v", settings.UserName, err)
+			continue
+		}
+		since := now.Add(-every)
+		if settings.LastSentAt.Valid {
+			if now.Before(settings.LastSentAt.Time.Add(every)) {
+				continue
+			}
+			since = settings.LastSentAt.Time
+		}
+
+		user := database.User{ID: settings.UserID, Name: settings.UserName}
+		// agg polls, posts published before the last digest may only be
+		// stored after it
+		d, err := buildDigest(s, user, since, true)
+		if err != nil {
+			log.Printf("couldn't build digest of %s: %v", settings.UserName, err)
+			continue
+		}
+
+		posts := 0
+		for _, feed := range d.Feeds {
+			posts += len(feed.Posts)
+		}
+		// empty digests aren't sent but still move the period on
+		if posts > 0 {
+			var body bytes.Buffer
+			err = writeDigest(&body, settings.Format, d)
+			if err != nil {
+				log.Printf("couldn't write digest of %s: %v", settings.UserName, err)
+				continue
+			}
+			subject := fmt.Sprintf("gator digest: %d new posts", posts)
+			err = m.send(settings.Email, subject, settings.Format, body.Bytes())
+			if err != nil {
+				log.Printf("couldn't send digest to %s: %v", settings.Email, err)
+				continue
+			}
+			log.Printf("sent digest of %d posts to %s", posts, settings.Email)
+		}
+
+		err = s.db.MarkEmailDigestSent(context.Background(), database.MarkEmailDigestSentParams{
+			LastSentAt: sql.NullTime{Time: now, Valid: true},
+			UpdatedAt:  now,
+			UserID:     settings.UserID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't record digest of %s: %w", settings.UserName, err)
+		}
+	}
+	return nil
+}
+
+// send delivers a message in the digest format to the address to.
+func (m *mailer) send(to, subject, format string, body []byte) error {
+	msg, err := m.message(to, subject, format, body)
+	if err != nil {
+		return err
+	}
+
+	port := m.cfg.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", addr, smtpTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		tlsConfig := &tls.Config{}
+		if m.tlsConfig != nil {
+			tlsConfig = m.tlsConfig.Clone()
+		}
+		tlsConfig.ServerName = m.cfg.Host
+		err = c.StartTLS(tlsConfig)
+		if err != nil {
+			return fmt.Errorf("couldn't start tls: %w", err)
+		}
+	} else if !isLocalhost(m.cfg.Host) {
+		return fmt.Errorf("%s doesn't support STARTTLS", addr)
+	}
+
+	if m.cfg.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host))
+		if err != nil {
+			return fmt.Errorf("couldn't authenticate: %w", err)
+		}
+	}
+
+	from, err := mail.ParseAddress(m.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address %s", m.cfg.From)
+	}
+	err = c.Mail(from.Address)
+	if err != nil {
+		return err
+	}
+	err = c.Rcpt(to)
+	if err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (m *mailer) message(to, subject, format string, body []byte) ([]byte, error) {
+	contentType := "text/plain; charset=utf-8"
+	if format == "html" {
+		contentType = "text/html; charset=utf-8"
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", m.cfg.From},
+		{"To", to},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", contentType},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, header := range headers {
+		if strings.ContainsAny(header[1], "\r\n") {
+			return nil, fmt.Errorf("invalid %s header", header[0])
+		}
+		fmt.Fprintf(&msg, "%s: %s\r\n", header[0], header[1])
+	}
+	msg.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&msg)
+	_, err := qp.Write(body)
+	if err != nil {
+		return nil, err
+	}
+	err = qp.Close()
+	if err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+func isLocalhost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// parseSchedule returns the time between two digests of a schedule:
+// daily, weekly or a duration like 12h or 3d.
+func parseSchedule(schedule string) (time.Duration, error) {
+	switch schedule {
+	case "daily":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+	if days, ok := strings.CutSuffix(schedule, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(schedule)
+	if err != nil || d < time.Hour {
+		return 0, fmt.Errorf("invalid schedule %s, please use daily, weekly or a duration of at least 1h", schedule)
+	}
+	return d, nil
+}
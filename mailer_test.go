@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arglp/gator/internal/config"
+	"github.com/arglp/gator/internal/database"
+)
+
+type sinkMessage struct {
+	from string
+	to   []string
+	auth string
+	tls  bool
+	data string
+}
+
+// smtpSink is a minimal SMTP server that keeps the messages it receives.
+type smtpSink struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+
+	mu       sync.Mutex
+	messages []sinkMessage
+}
+
+// newSMTPSink starts a sink on localhost, with a certificate the returned
+// client config trusts if withTLS is set.
+func newSMTPSink(t *testing.T, withTLS bool) (*smtpSink, *tls.Config) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &smtpSink{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	var clientConfig *tls.Config
+	if withTLS {
+		cert, pool := testCertificate(t)
+		sink.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		clientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	return sink, clientConfig
+}
+
+func (sink *smtpSink) port() int {
+	return sink.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (sink *smtpSink) received() []sinkMessage {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	return slices.Clone(sink.messages)
+}
+
+func (sink *smtpSink) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 sink ESMTP")
+
+	var msg sinkMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			if sink.tlsConfig != nil && !msg.tls {
+				tp.PrintfLine("250-sink")
+				tp.PrintfLine("250-STARTTLS")
+			} else {
+				tp.PrintfLine("250-sink")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, sink.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			msg.tls = true
+		case "AUTH":
+			_, initial, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(initial)
+			msg.auth = string(decoded)
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			sink.mu.Lock()
+			sink.messages = append(sink.messages, msg)
+			sink.mu.Unlock()
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// body decodes the quoted-printable body of a message.
+func body(t *testing.T, data string) string {
+	t.Helper()
+	_, encoded, ok := strings.Cut(data, "\n\n")
+	if !ok {
+		t.Fatalf("message without body:\n%s", data)
+	}
+	decoded := new(strings.Builder)
+	_, err := bufio.NewReader(quotedprintable.NewReader(strings.NewReader(encoded))).WriteTo(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded.String()
+}
+
+func TestMailerSend(t *testing.T) {
+	sink, clientConfig := newSMTPSink(t, true)
+	m := &mailer{
+		cfg: config.SMTPConfig{
+			Host:     "127.0.0.1",
+			Port:     sink.port(),
+			Username: "gator",
+			Password: "secret",
+			From:     "Gator <gator@example.com>",
+		},
+		tlsConfig: clientConfig,
+	}
+
+	long := strings.Repeat("é", 100)
+	err := m.send("alice@example.com", "digest", "html", []byte("<p>"+long+"</p>\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := sink.received()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	msg := messages[0]
+	if !msg.tls {
+		t.Error("expected the connection to be upgraded with STARTTLS")
+	}
+	if msg.auth != "\x00gator\x00secret" {
+		t.Errorf("unexpected auth %q", msg.auth)
+	}
+	if msg.from != "gator@example.com" || len(msg.to) != 1 || msg.to[0] != "alice@example.com" {
+		t.Errorf("unexpected envelope from %s to %v", msg.from, msg.to)
+	}
+	for _, header := range []string{"From: Gator <gator@example.com>\n", "To: alice@example.com\n", "Subject: digest\n", "Content-Type: text/html; charset=utf-8\n"} {
+		if !strings.Contains(msg.data, header) {
+			t.Errorf("expected header %q in:\n%s", header, msg.data)
+		}
+	}
+	if got := body(t, msg.data); got != "<p>"+long+"</p>\n" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
+
+func TestMailerRejectsHeaderInjection(t *testing.T) {
+	m := &mailer{cfg: config.SMTPConfig{Host: "127.0.0.1", From: "gator@example.com"}}
+	_, err := m.message("alice@example.com\r\nBcc: eve@example.com", "digest", "text", nil)
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSendDueDigests(t *testing.T) {
+	sink, _ := newSMTPSink(t, false)
+	s, q := newTestState(t)
+	m := &mailer{cfg: config.SMTPConfig{Host: "127.0.0.1", Port: sink.port(), From: "gator@example.com"}}
+
+	now := time.Now().UTC()
+	alice := seedUser(q, "alice")
+	bob := seedUser(q, "bob")
+	blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+	seedFollow(q, alice, blog)
+	// posts are stored by agg once they are published, unless said otherwise
+	stored := func(post database.Post, at time.Time) {
+		q.posts[slices.IndexFunc(q.posts, func(p database.Post) bool { return p.ID == post.ID })].CreatedAt = at
+	}
+	stored(seedPost(q, blog, "fresh post", "https://example.com/1", now.Add(-time.Hour)), now.Add(-time.Hour))
+	stored(seedPost(q, blog, "old post", "https://example.com/2", now.Add(-48*time.Hour)), now.Add(-48*time.Hour))
+	q.emails = []database.EmailDigest{
+		{UserID: alice.ID, Email: "alice@example.com", Schedule: "daily", Format: "text"},
+		{UserID: bob.ID, Email: "bob@example.com", Schedule: "weekly", Format: "text"},
+	}
+
+	err := sendDueDigests(s, m, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := sink.received()
+	if len(messages) != 1 || messages[0].to[0] != "alice@example.com" {
+		t.Fatalf("expected one digest for alice, got %v", messages)
+	}
+	text := body(t, messages[0].data)
+	if !strings.Contains(text, "fresh post") || strings.Contains(text, "old post") {
+		t.Errorf("unexpected digest:\n%s", text)
+	}
+	if !strings.Contains(messages[0].data, "Subject: gator digest: 1 new posts") {
+		t.Errorf("unexpected subject in:\n%s", messages[0].data)
+	}
+	for _, settings := range q.emails {
+		if !settings.LastSentAt.Valid || !settings.LastSentAt.Time.Equal(now) {
+			t.Errorf("expected the digest of %s to be marked as sent, got %v", settings.Email, settings.LastSentAt)
+		}
+	}
+
+	// not due yet
+	err = sendDueDigests(s, m, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.received()) != 1 {
+		t.Errorf("expected no new digest, got %d messages", len(sink.received()))
+	}
+
+	// due again, only posts since the last digest
+	stored(seedPost(q, blog, "next post", "https://example.com/3", now.Add(2*time.Hour)), now.Add(2*time.Hour))
+	err = sendDueDigests(s, m, now.Add(25*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages = sink.received()
+	if len(messages) != 2 {
+		t.Fatalf("expected a second digest, got %d messages", len(messages))
+	}
+	text = body(t, messages[1].data)
+	if !strings.Contains(text, "next post") || strings.Contains(text, "fresh post") {
+		t.Errorf("unexpected digest:\n%s", text)
+	}
+
+	// published before the last digest but only fetched after it
+	stored(seedPost(q, blog, "late post", "https://example.com/4", now.Add(24*time.Hour)), now.Add(26*time.Hour))
+	err = sendDueDigests(s, m, now.Add(50*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages = sink.received()
+	if len(messages) != 3 {
+		t.Fatalf("expected a third digest, got %d messages", len(messages))
+	}
+	text = body(t, messages[2].data)
+	if !strings.Contains(text, "late post") || strings.Contains(text, "next post") {
+		t.Errorf("unexpected digest:\n%s", text)
+	}
+}
+
+func TestNewMailerNeedsConfig(t *testing.T) {
+	_, err := newMailer(&config.Config{})
+	if err == nil || !strings.Contains(err.Error(), "please configure the smtp host") {
+		t.Errorf("expected a config error, got %v", err)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{schedule: "daily", want: 24 * time.Hour},
+		{schedule: "weekly", want: 7 * 24 * time.Hour},
+		{schedule: "12h", want: 12 * time.Hour},
+		{schedule: "3d", want: 3 * 24 * time.Hour},
+		{schedule: "5m", wantErr: true},
+		{schedule: "monthly", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSchedule(tt.schedule)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("expected error for %s", tt.schedule)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseSchedule(%s) = %v, %v, want %v", tt.schedule, got, err, tt.want)
+		}
+	}
+}
+
+func TestHandlerEmail(t *testing.T) {
+	setup := func(q *fakeQuerier) { seedUser(q, "alice") }
+
+	runHandlerTests(t, middlewareLoggedIn(handlerEmail), []handlerTest{
+		{
+			name:        "usage",
+			currentUser: "alice",
+			setup:       setup,
+			wantErr:     "usage: email set <address>",
+		},
+		{
+			name:        "set",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"set", "Alice <alice@example.com>", "--every", "weekly", "--format", "text"},
+			wantOut:     []string{"sending text digests to alice@example.com weekly"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.emails) != 1 || q.emails[0].Email != "alice@example.com" || q.emails[0].Schedule != "weekly" {
+					t.Errorf("unexpected settings %v", q.emails)
+				}
+			},
+		},
+		{
+			name:        "invalid address",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"set", "alice"},
+			wantErr:     "invalid email address alice",
+		},
+		{
+			name:        "invalid schedule",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"set", "alice@example.com", "--every", "1m"},
+			wantErr:     "invalid schedule 1m",
+		},
+		{
+			name:        "show",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				q.emails = append(q.emails, database.EmailDigest{UserID: alice.ID, Email: "alice@example.com", Schedule: "daily", Format: "html"})
+			},
+			args:    []string{"show"},
+			wantOut: []string{"email: alice@example.com", "schedule: daily", "format: html"},
+		},
+		{
+			name:        "show without settings",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"show"},
+			wantOut:     []string{"no email digest set up"},
+		},
+		{
+			name:        "off",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				q.emails = append(q.emails, database.EmailDigest{UserID: alice.ID, Email: "alice@example.com", Schedule: "daily", Format: "html"})
+			},
+			args:    []string{"off"},
+			wantOut: []string{"email digest turned off"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.emails) != 0 {
+					t.Errorf("expected no settings, got %v", q.emails)
+				}
+			},
+		},
+	})
+}
@@ -12,8 +12,11 @@ import (
 )
 
 type state struct {
-	db 	*database.Queries
+	db 	database.Querier
+	// conn is the connection behind db, for transactions
+	conn *sql.DB
 	cfg *config.Config
+	output outputFormat
 }
 
 func main() {
@@ -33,6 +36,7 @@ func main() {
 	}
 	defer db.Close()
 	s.db = database.New(db)
+	s.conn = db
 
 	cmds := commands{
 		handlers: make(map[string]func(*state, command) error),
@@ -40,6 +44,7 @@ func main() {
 
 	cmds.register("login", handlerLogin)
 	cmds.register("register", handlerRegister)
+	cmds.register("unregister", middlewareLoggedIn(handlerUnregister))
 	cmds.register("reset", handlerReset)
 	cmds.register("users", handlerUsers)
 	cmds.register("agg", handlerAgg)
@@ -49,8 +54,34 @@ func main() {
 	cmds.register("following", middlewareLoggedIn(handlerFollowing))
 	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
 	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	cmds.register("serve-websub", handlerServeWebSub)
+	cmds.register("fulltext", middlewareLoggedIn(handlerFulltext))
+	cmds.register("archive", middlewareLoggedIn(handlerArchive))
+	cmds.register("read", middlewareLoggedIn(handlerRead))
+	cmds.register("unread", middlewareLoggedIn(handlerUnread))
+	cmds.register("markall", middlewareLoggedIn(handlerMarkAll))
+	cmds.register("star", middlewareLoggedIn(handlerStar))
+	cmds.register("unstar", middlewareLoggedIn(handlerUnstar))
+	cmds.register("starred", middlewareLoggedIn(handlerStarred))
+	cmds.register("search", middlewareLoggedIn(handlerSearch))
+	cmds.register("tui", middlewareLoggedIn(handlerTUI))
+	cmds.register("digest", middlewareLoggedIn(handlerDigest))
+	cmds.register("email", middlewareLoggedIn(handlerEmail))
+	cmds.register("rule", middlewareLoggedIn(handlerRule))
+	cmds.register("import", middlewareLoggedIn(handlerImport))
+	cmds.register("export", middlewareLoggedIn(handlerExport))
+	cmds.register("tag", middlewareLoggedIn(handlerTag))
+	cmds.register("tags", middlewareLoggedIn(handlerTags))
+	cmds.register("feed", middlewareLoggedIn(handlerFeed))
+	cmds.register("dedupe", middlewareLoggedIn(handlerDedupe))
+	cmds.register("mailer", handlerMailer)
 
-	args := os.Args
+	output, args, err := extractOutputFlag(os.Args)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	s.output = output
 	if len(args) < 2 {
 		err = errors.New("please provide a command")
 		fmt.Println(err)
@@ -12,6 +12,9 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 		if err != nil {
 			return errors.New ("couldn't find user")
 		}
+		if user.ID == systemUserID {
+			return errors.New("the system user can't be used, please log in as another user")
+		}
 		err = handler(s, cmd, user)
 		if err != nil {
 			return err
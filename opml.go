@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+type opml struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// opmlOutline is a feed if it has an xmlUrl, otherwise a folder of the
+// outlines it contains.
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// opmlFeed is a feed of an OPML document with the path of the folders
+// it is in, joined by slashes.
+type opmlFeed struct {
+	Name   string
+	URL    string
+	Folder string
+}
+
+func (o opmlOutline) name() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+func handlerExport(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 || cmd.args[0] != "opml" {
+		return errors.New("usage: export opml [--all]")
+	}
+	fs := newFlagSet("export opml")
+	all := fs.Bool("all", false, "export every feed instead of the followed ones")
+	_, err := parseFlags(fs, cmd.args[1:])
+	if err != nil {
+		return err
+	}
+
+	var feeds []database.Feed
+	title := fmt.Sprintf("feeds followed by %s", user.Name)
+	if *all {
+		rows, err := s.db.GetFeeds(context.Background())
+		if err != nil {
+			return fmt.Errorf("couldn't get feeds: %w", err)
+		}
+		for _, row := range rows {
+			feeds = append(feeds, database.Feed{ID: row.ID, Name: row.Name, Url: row.Url})
+		}
+		title = "all feeds"
+	} else {
+		follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't get followed feeds: %w", err)
+		}
+		for _, follow := range follows {
+			feeds = append(feeds, database.Feed{ID: follow.FeedID, Name: follow.FeedName, Url: follow.FeedUrl})
+		}
+	}
+
+	tags, err := s.db.GetTagsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get tags: %w", err)
+	}
+
+	doc := opml{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       "gator: " + title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+		Body: opmlBody{Outlines: opmlOutlines(feeds, tags)},
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldn't write opml: %w", err)
+	}
+	fmt.Print(xml.Header)
+	fmt.Println(string(data))
+	return nil
+}
+
+// opmlOutlines puts feeds into the folders of their tags, nested tags
+// like folder/subfolder into nested folders. Feeds with several tags are
+// in several folders, feeds without tags at the top.
+func opmlOutlines(feeds []database.Feed, tags []database.GetTagsForUserRow) []opmlOutline {
+	var outlines []opmlOutline
+	for _, feed := range feeds {
+		outline := opmlOutline{
+			Text:   feed.Name,
+			Title:  feed.Name,
+			Type:   "rss",
+			XMLURL: feed.Url,
+		}
+		tagged := false
+		for _, tag := range tags {
+			if tag.FeedID == feed.ID {
+				addToFolder(&outlines, strings.Split(tag.Name, "/"), outline)
+				tagged = true
+			}
+		}
+		if !tagged {
+			outlines = append(outlines, outline)
+		}
+	}
+	return outlines
+}
+
+func addToFolder(outlines *[]opmlOutline, folders []string, outline opmlOutline) {
+	if len(folders) == 0 {
+		*outlines = append(*outlines, outline)
+		return
+	}
+	i := slices.IndexFunc(*outlines, func(o opmlOutline) bool {
+		return o.XMLURL == "" && o.Text == folders[0]
+	})
+	if i < 0 {
+		*outlines = append(*outlines, opmlOutline{Text: folders[0], Title: folders[0]})
+		i = len(*outlines) - 1
+	}
+	addToFolder(&(*outlines)[i].Outlines, folders[1:], outline)
+}
+
+func handlerImport(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 || cmd.args[0] != "opml" {
+		return errors.New("usage: import opml <file>")
+	}
+
+	data, err := os.ReadFile(cmd.args[1])
+	if err != nil {
+		return fmt.Errorf("couldn't read %s: %w", cmd.args[1], err)
+	}
+	var doc opml
+	err = xml.Unmarshal(data, &doc)
+	if err != nil {
+		return fmt.Errorf("couldn't parse %s: %w", cmd.args[1], err)
+	}
+
+	feeds, invalid := opmlFeeds(doc.Body.Outlines, "")
+	for _, reason := range invalid {
+		fmt.Printf("invalid: %s\n", reason)
+	}
+
+	created, existing, untagged := 0, 0, 0
+	for _, entry := range feeds {
+		followID, isNew, err := importFeed(s, user, entry)
+		if err != nil {
+			fmt.Printf("invalid: %s (%s): %v\n", entry.Name, entry.URL, err)
+			invalid = append(invalid, entry.URL)
+			continue
+		}
+		if isNew {
+			fmt.Printf("created: %s (%s)\n", entry.Name, entry.URL)
+			created++
+		} else {
+			fmt.Printf("existing: %s (%s)\n", entry.Name, entry.URL)
+			existing++
+		}
+		// the feed is followed even if it can't be tagged
+		err = tagImportedFeed(s, followID, entry)
+		if err != nil {
+			fmt.Printf("untagged: %s (%s): %v\n", entry.Name, entry.URL, err)
+			untagged++
+		}
+	}
+
+	summary := fmt.Sprintf("imported %d feeds: %d created, %d existing, %d invalid", created+existing, created, existing, len(invalid))
+	if untagged > 0 {
+		summary += fmt.Sprintf(", %d untagged", untagged)
+	}
+	fmt.Println(summary)
+	return nil
+}
+
+// opmlFeeds flattens outlines into the feeds they contain and the reasons
+// the entries that aren't valid feeds were skipped.
+func opmlFeeds(outlines []opmlOutline, folder string) ([]opmlFeed, []string) {
+	var feeds []opmlFeed
+	var invalid []string
+	for _, outline := range outlines {
+		name := strings.TrimSpace(outline.name())
+		if outline.XMLURL == "" {
+			if len(outline.Outlines) == 0 {
+				invalid = append(invalid, fmt.Sprintf("%q has no xmlUrl", name))
+				continue
+			}
+			sub := name
+			if folder != "" {
+				sub = folder + "/" + name
+			}
+			subFeeds, subInvalid := opmlFeeds(outline.Outlines, sub)
+			feeds = append(feeds, subFeeds...)
+			invalid = append(invalid, subInvalid...)
+			continue
+		}
+
+		feedURL := strings.TrimSpace(outline.XMLURL)
+		parsed, err := url.Parse(feedURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			invalid = append(invalid, fmt.Sprintf("%q has an invalid xmlUrl %s", name, feedURL))
+			continue
+		}
+		if name == "" {
+			name = feedURL
+		}
+		feeds = append(feeds, opmlFeed{Name: name, URL: feedURL, Folder: folder})
+	}
+	return feeds, invalid
+}
+
+// importFeed follows the feed of an OPML entry, creating it if it doesn't
+// exist. It returns the follow and reports whether the feed was created.
+func importFeed(s *state, user database.User, entry opmlFeed) (uuid.UUID, bool, error) {
+	var followID uuid.UUID
+	created := false
+
+	feed, err := getFeedByURL(s, entry.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, newFollow, err := addFeed(s, user, entry.Name, entry.URL)
+		if err != nil {
+			return uuid.Nil, false, fmt.Errorf("couldn't add feed: %w", err)
+		}
+		followID = newFollow.ID
+		created = true
+	} else if err != nil {
+		return uuid.Nil, false, fmt.Errorf("couldn't get feed: %w", err)
+	} else {
+		follow, err := s.db.GetFeedFollow(context.Background(), database.GetFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			newFollow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now().UTC(),
+				UpdatedAt: time.Now().UTC(),
+				UserID:    user.ID,
+				FeedID:    feed.ID,
+			})
+			if err != nil {
+				return uuid.Nil, false, fmt.Errorf("couldn't follow feed: %w", err)
+			}
+			followID = newFollow.ID
+		} else if err != nil {
+			return uuid.Nil, false, fmt.Errorf("couldn't get feed follow: %w", err)
+		} else {
+			followID = follow.ID
+		}
+	}
+
+	return followID, created, nil
+}
+
+// tagImportedFeed tags the follow of an OPML entry with the folder of the
+// entry, entries outside of folders aren't tagged.
+func tagImportedFeed(s *state, followID uuid.UUID, entry opmlFeed) error {
+	tag, err := normalizeTag(entry.Folder)
+	if err != nil {
+		return nil
+	}
+	err = s.db.AddTag(context.Background(), database.AddTagParams{
+		FeedFollowID: followID,
+		Name:         tag,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't tag feed with %s: %w", tag, err)
+	}
+	return nil
+}
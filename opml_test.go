@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/arglp/gator/internal/database"
+)
+
+const testOPML = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>subscriptions</title></head>
+  <body>
+    <outline text="Blog" title="The Blog" type="rss" xmlUrl="https://example.com/rss" htmlUrl="https://example.com"/>
+    <outline text="Work">
+      <outline text="News" xmlUrl="https://news.example.com/rss"/>
+      <outline text="Security">
+        <outline text="Advisories" xmlUrl="https://advisories.example.com/feed"/>
+      </outline>
+    </outline>
+    <outline text="Broken" xmlUrl="ftp://example.com/feed"/>
+    <outline text="Separator"/>
+  </body>
+</opml>
+`
+
+func writeOPML(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	err := os.WriteFile(path, []byte(data), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHandlerImport(t *testing.T) {
+	path := writeOPML(t, testOPML)
+
+	runHandlerTests(t, middlewareLoggedIn(handlerImport), []handlerTest{
+		{
+			name:        "usage",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:        []string{"csv", path},
+			wantErr:     "usage: import opml <file>",
+		},
+		{
+			name:        "missing file",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:        []string{"opml", filepath.Join(t.TempDir(), "missing.opml")},
+			wantErr:     "couldn't read",
+		},
+		{
+			name:        "invalid document",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			args:        []string{"opml", writeOPML(t, "<opml><body>")},
+			wantErr:     "couldn't parse",
+		},
+		{
+			name:        "import",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				bob := seedUser(q, "bob")
+				// followed already
+				blog := seedFeed(q, bob, "blog", "https://example.com/rss")
+				seedFollow(q, alice, blog)
+				// exists but isn't followed
+				seedFeed(q, bob, "news", "https://news.example.com/rss")
+			},
+			args: []string{"opml", path},
+			wantOut: []string{
+				"existing: The Blog (https://example.com/rss)",
+				"existing: News (https://news.example.com/rss)",
+				"created: Advisories (https://advisories.example.com/feed)",
+				`invalid: "Broken" has an invalid xmlUrl ftp://example.com/feed`,
+				`invalid: "Separator" has no xmlUrl`,
+				"imported 3 feeds: 1 created, 2 existing, 2 invalid",
+			},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.feeds) != 3 {
+					t.Fatalf("expected 3 feeds, got %d", len(q.feeds))
+				}
+				created := q.feeds[2]
+				if created.Name != "Advisories" || created.UserID != q.users[0].ID {
+					t.Errorf("unexpected feed %v", created)
+				}
+
+				alice := q.users[0].ID
+				tags := map[string][]string{}
+				for _, follow := range q.follows {
+					if follow.UserID != alice {
+						continue
+					}
+					feed, _ := q.feedByID(follow.FeedID)
+					tags[feed.Url] = nil
+					for _, tag := range q.tags {
+						if tag.FeedFollowID == follow.ID {
+							tags[feed.Url] = append(tags[feed.Url], tag.Name)
+						}
+					}
+				}
+				want := map[string][]string{
+					"https://example.com/rss":             nil,
+					"https://news.example.com/rss":        {"Work"},
+					"https://advisories.example.com/feed": {"Work/Security"},
+				}
+				if len(tags) != len(want) {
+					t.Fatalf("expected %d follows, got %v", len(want), tags)
+				}
+				for url, wantTags := range want {
+					if !slices.Equal(tags[url], wantTags) {
+						t.Errorf("expected tags %v for %s, got %v", wantTags, url, tags[url])
+					}
+				}
+			},
+		},
+	})
+}
+
+func TestHandlerExport(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		bob := seedUser(q, "bob")
+		blog := seedFeed(q, alice, "blog & more", "https://example.com/rss")
+		news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+		advisories := seedFeed(q, alice, "advisories", "https://advisories.example.com/feed")
+		seedFeed(q, bob, "unfollowed", "https://bob.example.com/rss")
+		seedFollow(q, alice, blog)
+		newsFollow := seedFollow(q, alice, news)
+		advisoriesFollow := seedFollow(q, alice, advisories)
+		q.tags = append(q.tags,
+			database.Tag{FeedFollowID: newsFollow.ID, Name: "work"},
+			database.Tag{FeedFollowID: advisoriesFollow.ID, Name: "work/security"},
+		)
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerExport), []handlerTest{
+		{
+			name:        "usage",
+			currentUser: "alice",
+			setup:       setup,
+			wantErr:     "usage: export opml [--all]",
+		},
+		{
+			name:        "followed feeds",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"opml"},
+			wantOut: []string{
+				`<?xml version="1.0" encoding="UTF-8"?>`,
+				`<opml version="2.0">`,
+				`<title>gator: feeds followed by alice</title>`,
+				`<outline text="blog &amp; more" title="blog &amp; more" type="rss" xmlUrl="https://example.com/rss"></outline>`,
+			},
+			notOut: []string{"unfollowed"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				// the export can be imported again with the same folders
+				out, err := captureStdout(t, func() error {
+					return handlerExport(s, command{name: "export", args: []string{"opml"}}, q.users[0])
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				var doc opml
+				err = xml.Unmarshal([]byte(out), &doc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				feeds, invalid := opmlFeeds(doc.Body.Outlines, "")
+				want := []opmlFeed{
+					{Name: "blog & more", URL: "https://example.com/rss"},
+					{Name: "news", URL: "https://news.example.com/rss", Folder: "work"},
+					{Name: "advisories", URL: "https://advisories.example.com/feed", Folder: "work/security"},
+				}
+				if len(invalid) != 0 || !slices.Equal(feeds, want) {
+					t.Errorf("expected %v, got %v %v", want, feeds, invalid)
+				}
+			},
+		},
+		{
+			name:        "all feeds",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"opml", "--all"},
+			wantOut:     []string{"<title>gator: all feeds</title>", `xmlUrl="https://bob.example.com/rss"`, `<outline text="work" title="work">`},
+		},
+	})
+}
+
+// failingTags is a fakeQuerier whose tags can't be stored.
+type failingTags struct {
+	*fakeQuerier
+}
+
+func (q failingTags) AddTag(ctx context.Context, arg database.AddTagParams) error {
+	return errors.New("tags are broken")
+}
+
+func TestHandlerImportTagFailure(t *testing.T) {
+	s, q := newTestState(t)
+	s.db = failingTags{q}
+	alice := seedUser(q, "alice")
+
+	out, err := captureStdout(t, func() error {
+		return handlerImport(s, command{name: "import", args: []string{"opml", writeOPML(t, testOPML)}}, alice)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"created: News (https://news.example.com/rss)",
+		"untagged: News (https://news.example.com/rss): couldn't tag feed with Work: tags are broken",
+		"imported 3 feeds: 3 created, 0 existing, 2 invalid, 2 untagged",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if len(q.follows) != 3 {
+		t.Errorf("expected the untagged feeds to stay followed, got %d follows", len(q.follows))
+	}
+}
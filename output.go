@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// outputFormat selects how listing commands print their records. The
+// zero value is the text output meant for reading in the terminal.
+type outputFormat string
+
+const (
+	outputText   outputFormat = ""
+	outputTable  outputFormat = "table"
+	outputJSON   outputFormat = "json"
+	outputNDJSON outputFormat = "ndjson"
+	outputCSV    outputFormat = "csv"
+)
+
+func parseOutputFormat(value string) (outputFormat, error) {
+	switch value {
+	case "text":
+		return outputText, nil
+	case "table", "json", "ndjson", "csv":
+		return outputFormat(value), nil
+	}
+	return outputText, fmt.Errorf("unknown output format %s, please use text, table, json, ndjson or csv", value)
+}
+
+// extractOutputFlag removes the global --output option from args, it may
+// come before or after the command.
+func extractOutputFlag(args []string) (outputFormat, []string, error) {
+	format := outputText
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "output" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return outputText, nil, fmt.Errorf("please provide an output format")
+			}
+			i++
+			value = args[i]
+		}
+		var err error
+		format, err = parseOutputFormat(value)
+		if err != nil {
+			return outputText, nil, err
+		}
+	}
+	return format, rest, nil
+}
+
+// writeRecords writes records in a structured format. The columns of
+// table and csv output are the json names of the fields of T, fields
+// tagged with table:"-" are left out of tables.
+func writeRecords[T any](w io.Writer, format outputFormat, records []T) error {
+	switch format {
+	case outputJSON:
+		if records == nil {
+			records = []T{}
+		}
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case outputNDJSON:
+		enc := json.NewEncoder(w)
+		for _, record := range records {
+			err := enc.Encode(record)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputCSV:
+		columns := recordColumns(reflect.TypeFor[T](), false)
+		cw := csv.NewWriter(w)
+		err := cw.Write(columnNames(columns))
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			err = cw.Write(recordValues(reflect.ValueOf(record), columns, false))
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case outputTable:
+		columns := recordColumns(reflect.TypeFor[T](), true)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(columnNames(columns), "\t")))
+		for _, record := range records {
+			fmt.Fprintln(tw, strings.Join(recordValues(reflect.ValueOf(record), columns, true), "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %s", format)
+}
+
+type recordColumn struct {
+	name  string
+	index int
+}
+
+func recordColumns(t reflect.Type, table bool) []recordColumn {
+	var columns []recordColumn
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if table && field.Tag.Get("table") == "-" {
+			continue
+		}
+		columns = append(columns, recordColumn{name: name, index: i})
+	}
+	return columns
+}
+
+func columnNames(columns []recordColumn) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	return names
+}
+
+func recordValues(v reflect.Value, columns []recordColumn, table bool) []string {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = formatRecordValue(v.Field(column.index), table)
+	}
+	return values
+}
+
+func formatRecordValue(v reflect.Value, table bool) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if table {
+			return value.Local().Format("2006-01-02 15:04")
+		}
+		return value.Format(time.RFC3339Nano)
+	case string:
+		if table {
+			// a table row has to stay on one line
+			return strings.Join(strings.Fields(value), " ")
+		}
+		return value
+	case []string:
+		return strings.Join(value, ", ")
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// nullTime returns a pointer to the time if it is set, records use nil
+// for missing times.
+func nullTime(t time.Time, valid bool) *time.Time {
+	if !valid {
+		return nil
+	}
+	return &t
+}
+
+func nullString(s string, valid bool) *string {
+	if !valid {
+		return nil
+	}
+	return &s
+}
+
+type userRecord struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Current   bool      `json:"current"`
+}
+
+type feedRecord struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	UserID        uuid.UUID  `json:"user_id" table:"-"`
+	UserName      string     `json:"user_name"`
+	Followers     int64      `json:"followers"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	Title         *string    `json:"title"`
+	SiteURL       *string    `json:"site_url" table:"-"`
+	Description   *string    `json:"description" table:"-"`
+	Language      *string    `json:"language" table:"-"`
+	ImageURL      *string    `json:"image_url" table:"-"`
+	Generator     *string    `json:"generator" table:"-"`
+}
+
+type followRecord struct {
+	ID              uuid.UUID `json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	UserID          uuid.UUID `json:"user_id" table:"-"`
+	UserName        string    `json:"user_name"`
+	FeedID          uuid.UUID `json:"feed_id"`
+	FeedName        string    `json:"feed_name"`
+	FeedURL         string    `json:"feed_url"`
+	FeedTitle       *string   `json:"feed_title"`
+	FeedSiteURL     *string   `json:"feed_site_url" table:"-"`
+	FeedDescription *string   `json:"feed_description" table:"-"`
+	FeedLanguage    *string   `json:"feed_language" table:"-"`
+	FeedImageURL    *string   `json:"feed_image_url" table:"-"`
+	FeedGenerator   *string   `json:"feed_generator" table:"-"`
+	Tags            []string  `json:"tags" table:"-"`
+}
+
+type postRecord struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	PublishedAt *time.Time `json:"published_at"`
+	FeedID      uuid.UUID  `json:"feed_id" table:"-"`
+	FeedName    string     `json:"feed_name"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description *string    `json:"description" table:"-"`
+	Content     *string    `json:"content" table:"-"`
+	ArchivePath *string    `json:"archive_path" table:"-"`
+	ReadAt      *time.Time `json:"read_at"`
+	StarredAt   *time.Time `json:"starred_at"`
+	// position of the post for browse --before and --after
+	Cursor string `json:"cursor" table:"-"`
+}
+
+type searchRecord struct {
+	ID          uuid.UUID  `json:"id"`
+	PublishedAt *time.Time `json:"published_at"`
+	FeedName    string     `json:"feed_name"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Rank        float32    `json:"rank"`
+	Snippet     string     `json:"snippet" table:"-"`
+}
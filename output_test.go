@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestExtractOutputFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		want     outputFormat
+		wantArgs []string
+		wantErr  string
+	}{
+		{
+			name:     "no option",
+			args:     []string{"gator", "users"},
+			want:     outputText,
+			wantArgs: []string{"gator", "users"},
+		},
+		{
+			name:     "before the command",
+			args:     []string{"gator", "--output", "json", "users"},
+			want:     outputJSON,
+			wantArgs: []string{"gator", "users"},
+		},
+		{
+			name:     "after the command",
+			args:     []string{"gator", "browse", "5", "-output=csv", "--all"},
+			want:     outputCSV,
+			wantArgs: []string{"gator", "browse", "5", "--all"},
+		},
+		{
+			name:     "text",
+			args:     []string{"gator", "feeds", "--output", "text"},
+			want:     outputText,
+			wantArgs: []string{"gator", "feeds"},
+		},
+		{
+			name:     "after --",
+			args:     []string{"gator", "search", "--", "--output", "json"},
+			want:     outputText,
+			wantArgs: []string{"gator", "search", "--", "--output", "json"},
+		},
+		{
+			name:    "unknown format",
+			args:    []string{"gator", "users", "--output", "xml"},
+			wantErr: "unknown output format xml, please use text, table, json, ndjson or csv",
+		},
+		{
+			name:    "missing format",
+			args:    []string{"gator", "users", "--output"},
+			wantErr: "please provide an output format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := extractOutputFlag(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected format %q, got %q", tt.want, got)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("expected args %v, got %v", tt.wantArgs, args)
+			}
+		})
+	}
+}
+
+func TestWriteRecords(t *testing.T) {
+	type record struct {
+		ID       uuid.UUID  `json:"id"`
+		At       time.Time  `json:"at"`
+		Name     string     `json:"name"`
+		Optional *time.Time `json:"optional"`
+		Long     string     `json:"long" table:"-"`
+	}
+	at := time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC)
+	records := []record{
+		{ID: uuid.MustParse("0d4c8a2e-6f1b-4b8e-9a53-1f0e2d3c4b5a"), At: at, Name: "a, \"quoted\"", Long: "line\nbreak"},
+		{ID: uuid.MustParse("1d4c8a2e-6f1b-4b8e-9a53-1f0e2d3c4b5a"), At: at, Name: "b", Optional: &at},
+	}
+
+	tests := []struct {
+		format outputFormat
+		want   string
+	}{
+		{
+			format: outputCSV,
+			want: "id,at,name,optional,long\n" +
+				"0d4c8a2e-6f1b-4b8e-9a53-1f0e2d3c4b5a,2026-10-01T08:30:00Z,\"a, \"\"quoted\"\"\",,\"line\nbreak\"\n" +
+				"1d4c8a2e-6f1b-4b8e-9a53-1f0e2d3c4b5a,2026-10-01T08:30:00Z,b,2026-10-01T08:30:00Z,\n",
+		},
+		{
+			format: outputNDJSON,
+			want: `{"id":"0d4c8a2e-6f1b-4b8e-9a53-1f0e2d3c4b5a","at":"2026-10-01T08:30:00Z","name":"a, \"quoted\"","optional":null,"long":"line\nbreak"}` + "\n" +
+				`{"id":"1d4c8a2e-6f1b-4b8e-9a53-1f0e2d3c4b5a","at":"2026-10-01T08:30:00Z","name":"b","optional":"2026-10-01T08:30:00Z","long":""}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeRecords(&buf, tt.format, records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, buf.String())
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeRecords(&buf, outputJSON, records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded []map[string]any
+		err = json.Unmarshal(buf.Bytes(), &decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded) != 2 || decoded[0]["name"] != "a, \"quoted\"" || decoded[0]["optional"] != nil {
+			t.Errorf("unexpected json %s", buf.String())
+		}
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeRecords[record](&buf, outputJSON, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "[]\n" {
+			t.Errorf("expected an empty array, got %q", buf.String())
+		}
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeRecords(&buf, outputTable, records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected a header and two rows, got:\n%s", buf.String())
+		}
+		if strings.Fields(lines[0])[0] != "ID" || strings.Contains(lines[0], "LONG") {
+			t.Errorf("unexpected header %q", lines[0])
+		}
+		if !strings.Contains(lines[2], at.Local().Format("2006-01-02 15:04")) {
+			t.Errorf("expected the optional time in the row, got %q", lines[2])
+		}
+	})
+}
+
+func TestHandlersOutput(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		seedFollow(q, alice, blog)
+		post := seedPost(q, blog, "post", "https://example.com/1", time.Now())
+		seedStar(q, alice, post)
+	}
+
+	tests := []struct {
+		name    string
+		handler func(*state, command) error
+		output  outputFormat
+		wantOut []string
+	}{
+		{
+			name:    "users",
+			handler: handlerUsers,
+			output:  outputJSON,
+			wantOut: []string{`"name": "alice"`, `"current": true`, `"created_at": "`},
+		},
+		{
+			name:    "feeds",
+			handler: handlerFeeds,
+			output:  outputNDJSON,
+			wantOut: []string{`"url":"https://example.com/rss"`, `"user_name":"alice"`, `"last_fetched_at":null`, `"title":null`, `"image_url":null`},
+		},
+		{
+			name:    "following",
+			handler: middlewareLoggedIn(handlerFollowing),
+			output:  outputCSV,
+			wantOut: []string{"id,created_at,updated_at,user_id,user_name,feed_id,feed_name,feed_url,feed_title,feed_site_url,feed_description,feed_language,feed_image_url,feed_generator,tags\n", ",alice,", ",blog,https://example.com/rss,,,,,,,\n"},
+		},
+		{
+			name:    "browse",
+			handler: middlewareLoggedIn(handlerBrowse),
+			output:  outputJSON,
+			wantOut: []string{`"title": "post"`, `"feed_name": "blog"`, `"read_at": null`, `"cursor": "`},
+		},
+		{
+			name:    "starred",
+			handler: middlewareLoggedIn(handlerStarred),
+			output:  outputTable,
+			wantOut: []string{"TITLE", "STARRED_AT", "blog"},
+		},
+	}
+
+	for _, tt := range tests {
+		runHandlerTests(t, tt.handler, []handlerTest{
+			{
+				name:        tt.name,
+				currentUser: "alice",
+				output:      tt.output,
+				setup:       setup,
+				wantOut:     tt.wantOut,
+				notOut:      []string{"title: ", "registered users"},
+			},
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// The system user owns the feeds of deleted users that nobody else
+// follows. Anyone can take these feeds over with feed chown.
+const systemUserName = "gator"
+
+var systemUserID = uuid.Nil
+
+// ensureSystemUser creates the system user if it doesn't exist yet. A user
+// registered as gator before the name was reserved has to be unregistered
+// first, the system user would get the wrong account by name otherwise.
+func ensureSystemUser(s *state) (database.User, error) {
+	other, err := s.db.GetUser(context.Background(), systemUserName)
+	if err == nil && other.ID != systemUserID {
+		return database.User{}, fmt.Errorf("the name of the system user is taken by the user %s, registered before it was reserved, who has to unregister first", systemUserName)
+	}
+	user, err := s.db.EnsureSystemUser(context.Background(), database.EnsureSystemUserParams{
+		ID:        systemUserID,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name:      systemUserName,
+	})
+	if err != nil {
+		return database.User{}, fmt.Errorf("couldn't create the system user %s: %w", systemUserName, err)
+	}
+	return user, nil
+}
+
+// handlerUnregister deletes the active user, the feeds the user added are
+// handed over to their first other follower or to the system user.
+func handlerUnregister(s *state, cmd command, user database.User) error {
+	if len(cmd.args) > 1 || (len(cmd.args) == 1 && cmd.args[0] != user.Name) {
+		return errors.New("usage: unregister [name], only the active user can be unregistered")
+	}
+	name := user.Name
+
+	all, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("couldn't get feeds: %w", err)
+	}
+	owned := slices.ContainsFunc(all, func(feed database.GetFeedsRow) bool {
+		return feed.UserID == user.ID
+	})
+	// the system user is only needed for feeds nobody else follows
+	if owned && name == systemUserName {
+		return fmt.Errorf("%s has the name of the system user, please hand your feeds over with feed chown before unregistering", name)
+	}
+	if owned {
+		_, err = ensureSystemUser(s)
+		if err != nil {
+			return err
+		}
+	}
+	// the feeds are only handed over if the user is deleted with them
+	var feeds []database.ReassignFeedsOfUserRow
+	err = s.inTx(func(q database.Querier) error {
+		var err error
+		feeds, err = q.ReassignFeedsOfUser(context.Background(), database.ReassignFeedsOfUserParams{
+			SystemUserID: systemUserID,
+			UpdatedAt:    time.Now().UTC(),
+			UserID:       user.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't hand over the feeds of %s: %w", name, err)
+		}
+		err = q.DeleteUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't delete user %s: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, feed := range feeds {
+		fmt.Printf("%s now belongs to %s\n", feed.Name, feed.UserName)
+	}
+	err = s.cfg.SetUser("")
+	if err != nil {
+		return fmt.Errorf("couldn't log out: %w", err)
+	}
+	fmt.Printf("deleted user %s\n", name)
+	return nil
+}
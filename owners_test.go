@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandlerUnregister(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		bob := seedUser(q, "bob")
+		carol := seedUser(q, "carol")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+		seedFeed(q, bob, "bobs feed", "https://bob.example.com/rss")
+		seedFollow(q, alice, blog)
+		follow := seedFollow(q, carol, blog)
+		follow.CreatedAt = time.Now().Add(-time.Hour)
+		q.follows[1] = follow
+		seedFollow(q, bob, blog)
+		seedFollow(q, alice, news)
+		seedPost(q, news, "post", "https://news.example.com/1", time.Now())
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerUnregister), []handlerTest{
+		{
+			name:        "hands feeds over",
+			currentUser: "alice",
+			setup:       setup,
+			wantOut:     []string{"blog now belongs to carol\nnews now belongs to gator\ndeleted user alice\n"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				owners := map[string]string{}
+				for _, feed := range q.feeds {
+					owner, _ := q.userByID(feed.UserID)
+					owners[feed.Name] = owner.Name
+				}
+				if owners["blog"] != "carol" || owners["news"] != "gator" || owners["bobs feed"] != "bob" {
+					t.Errorf("unexpected owners %v", owners)
+				}
+				if len(q.posts) != 1 {
+					t.Errorf("expected the posts to be kept, got %d", len(q.posts))
+				}
+				if len(q.follows) != 2 {
+					t.Errorf("expected the follows of alice to be removed, got %d follows", len(q.follows))
+				}
+				if _, ok := q.userByID(systemUserID); !ok {
+					t.Error("expected the system user to be created")
+				}
+				if s.cfg.CurrentUserName != "" {
+					t.Errorf("expected alice to be logged out, got %s", s.cfg.CurrentUserName)
+				}
+			},
+		},
+		{
+			name:        "by name",
+			currentUser: "bob",
+			setup:       setup,
+			args:        []string{"bob"},
+			wantOut:     []string{"bobs feed now belongs to gator", "deleted user bob"},
+		},
+		{
+			name:        "other user",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"bob"},
+			wantErr:     "only the active user can be unregistered",
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.users) != 3 {
+					t.Errorf("expected bob to be kept, got %v", q.users)
+				}
+			},
+		},
+		{
+			name:    "not logged in",
+			setup:   setup,
+			wantErr: "couldn't find user",
+		},
+		{
+			name:        "system user",
+			currentUser: "gator",
+			setup: func(q *fakeQuerier) {
+				user := seedUser(q, "gator")
+				user.ID = systemUserID
+				q.users[0] = user
+			},
+			wantErr: "the system user can't be used",
+		},
+		{
+			name:        "name of the system user taken",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				setup(q)
+				seedUser(q, "gator")
+			},
+			wantErr: "the name of the system user is taken by the user gator",
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.users) != 4 || q.feeds[0].UserID != q.users[0].ID {
+					t.Errorf("expected alice and her feeds to be kept")
+				}
+			},
+		},
+		{
+			name:        "user registered as gator before it was reserved",
+			currentUser: "gator",
+			setup:       func(q *fakeQuerier) { seedUser(q, "gator") },
+			wantOut:     []string{"deleted user gator"},
+		},
+	})
+}
+
+// failingDeleteUser is a fakeQuerier whose users can't be deleted.
+type failingDeleteUser struct {
+	*fakeQuerier
+}
+
+func (q failingDeleteUser) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return errors.New("users are stuck")
+}
+
+func TestHandlerUnregisterDeleteFailure(t *testing.T) {
+	s, q := newTestState(t)
+	s.db = failingDeleteUser{q}
+	alice := seedUser(q, "alice")
+	seedFeed(q, alice, "blog", "https://example.com/rss")
+	s.cfg.CurrentUserName = "alice"
+
+	out, err := captureStdout(t, func() error {
+		return handlerUnregister(s, command{name: "unregister"}, alice)
+	})
+	if err == nil || err.Error() != "couldn't delete user alice: users are stuck" {
+		t.Fatalf("expected the delete to fail, got %v", err)
+	}
+	if strings.Contains(out, "now belongs to") {
+		t.Errorf("expected no feeds to be reported as handed over, got %q", out)
+	}
+	if s.cfg.CurrentUserName != "alice" {
+		t.Errorf("expected alice to stay logged in, got %q", s.cfg.CurrentUserName)
+	}
+}
+
+func TestSystemUserIsHidden(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		seedUser(q, "alice")
+		system := seedUser(q, "gator")
+		system.ID = systemUserID
+		q.users[1] = system
+	}
+	runHandlerTests(t, handlerLogin, []handlerTest{
+		{
+			name:    "login",
+			setup:   setup,
+			args:    []string{"gator"},
+			wantErr: "gator is the system user and can't log in",
+		},
+	})
+	runHandlerTests(t, handlerUsers, []handlerTest{
+		{
+			name:        "users",
+			currentUser: "alice",
+			setup:       setup,
+			wantOut:     []string{"alice (current)"},
+			notOut:      []string{"gator"},
+		},
+	})
+}
+
+func TestHandlerRegisterReservesSystemUser(t *testing.T) {
+	runHandlerTests(t, handlerRegister, []handlerTest{
+		{
+			name:    "reserved name",
+			args:    []string{"gator"},
+			wantErr: "username gator is reserved",
+		},
+	})
+}
+
+func TestHandlerFeedChown(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		seedUser(q, "bob")
+		system := seedUser(q, "gator")
+		system.ID = systemUserID
+		q.users[2] = system
+		seedFeed(q, alice, "blog", "https://example.com/rss")
+		seedFeed(q, system, "orphan", "https://orphan.example.com/rss")
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerFeed), []handlerTest{
+		{
+			name:        "chown",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"chown", "blog", "bob"},
+			wantOut:     []string{"blog now belongs to bob"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if q.feeds[0].UserID != q.users[1].ID {
+					t.Errorf("expected bob to own blog")
+				}
+			},
+		},
+		{
+			name:        "not the owner",
+			currentUser: "bob",
+			setup:       setup,
+			args:        []string{"chown", "blog", "bob"},
+			wantErr:     "only the owner of blog can change it",
+		},
+		{
+			name:        "take over a feed of the system user",
+			currentUser: "bob",
+			setup:       setup,
+			args:        []string{"chown", "orphan", "bob"},
+			wantOut:     []string{"orphan now belongs to bob"},
+		},
+		{
+			name:        "unknown user",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"chown", "blog", "dave"},
+			wantErr:     "couldn't find user dave",
+		},
+	})
+}
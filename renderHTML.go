@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/term"
+)
+
+const (
+	defaultTerminalWidth = 80
+	minRenderWidth       = 20
+)
+
+// terminalWidth returns the width of the terminal stdout is attached to,
+// falling back to $COLUMNS and then to 80 columns.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err == nil && width > 0 {
+		return width
+	}
+	width, err = strconv.Atoi(os.Getenv("COLUMNS"))
+	if err == nil && width > 0 {
+		return width
+	}
+	return defaultTerminalWidth
+}
+
+// renderHTML renders an HTML fragment as plain text wrapped to width.
+// Paragraphs are separated by blank lines, list items get bullets or
+// numbers and links are replaced by footnotes listed at the end.
+func renderHTML(s string, width int) string {
+	nodes, err := parseHTMLFragment(s)
+	if err != nil {
+		return s
+	}
+
+	r := &textRenderer{width: max(width, minRenderWidth)}
+	for _, node := range nodes {
+		r.render(node)
+	}
+	r.flush()
+
+	if len(r.footnotes) > 0 {
+		r.gap = true
+		for i, footnote := range r.footnotes {
+			r.writeLine(fmt.Sprintf("[%d] %s", i+1, footnote))
+		}
+	}
+	return strings.TrimRight(r.out.String(), "\n")
+}
+
+type textRenderer struct {
+	width int
+	out   strings.Builder
+	// words of the paragraph being built
+	words []string
+	// whether the last text ended in whitespace
+	space bool
+	// prefix of every line of the current block
+	indent string
+	// prefix of the first line of the next paragraph, set by list items
+	bullet string
+	// a blank line is due before the next line
+	gap bool
+	// counters of the enclosing lists, 0 for unordered lists
+	lists     []int
+	pre       int
+	footnotes []string
+}
+
+func (r *textRenderer) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Template, atom.Noscript:
+		return
+	case atom.Br:
+		r.flush()
+		return
+	case atom.Hr:
+		r.block()
+		r.writeLine(strings.Repeat("-", min(r.width-utf8.RuneCountInString(r.indent), 40)))
+		r.gap = true
+		return
+	case atom.Img:
+		if alt := strings.TrimSpace(attrValue(n, "alt")); alt != "" {
+			r.text(" [image: " + alt + "] ")
+		}
+		return
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Table, atom.Figure, atom.Dl, atom.Section, atom.Article, atom.Header, atom.Footer:
+		r.block()
+		r.children(n)
+		r.block()
+		return
+	case atom.Tr, atom.Dt, atom.Dd, atom.Figcaption:
+		r.flush()
+		r.children(n)
+		r.flush()
+		return
+	case atom.Td, atom.Th:
+		r.children(n)
+		r.text(" ")
+		return
+	case atom.Blockquote:
+		r.block()
+		indent := r.indent
+		r.indent += "> "
+		r.children(n)
+		r.block()
+		r.indent = indent
+		return
+	case atom.Pre:
+		r.block()
+		r.pre++
+		r.children(n)
+		r.pre--
+		r.block()
+		return
+	case atom.Ul, atom.Ol:
+		if len(r.lists) == 0 {
+			r.block()
+		} else {
+			r.flush()
+		}
+		counter := 0
+		if n.DataAtom == atom.Ol {
+			counter = 1
+		}
+		r.lists = append(r.lists, counter)
+		r.children(n)
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.block()
+		} else {
+			r.flush()
+		}
+		return
+	case atom.Li:
+		r.flush()
+		marker := "• "
+		if depth := len(r.lists); depth > 0 && r.lists[depth-1] > 0 {
+			marker = strconv.Itoa(r.lists[depth-1]) + ". "
+			r.lists[depth-1]++
+		}
+		indent := r.indent
+		r.bullet = indent + marker
+		r.indent = indent + strings.Repeat(" ", utf8.RuneCountInString(marker))
+		r.children(n)
+		r.flush()
+		r.indent = indent
+		return
+	case atom.A:
+		r.children(n)
+		href := strings.TrimSpace(attrValue(n, "href"))
+		if href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			r.glue(fmt.Sprintf("[%d]", r.footnote(href)))
+		}
+		return
+	}
+
+	r.children(n)
+}
+
+func (r *textRenderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.render(c)
+	}
+}
+
+func (r *textRenderer) footnote(href string) int {
+	for i, footnote := range r.footnotes {
+		if footnote == href {
+			return i + 1
+		}
+	}
+	r.footnotes = append(r.footnotes, href)
+	return len(r.footnotes)
+}
+
+// text adds inline text to the current paragraph.
+func (r *textRenderer) text(s string) {
+	if r.pre > 0 {
+		r.preformatted(s)
+		return
+	}
+	if s == "" {
+		return
+	}
+
+	fields := strings.Fields(s)
+	startsWithSpace := unicode.IsSpace([]rune(s)[0])
+	if len(fields) > 0 && !startsWithSpace && !r.space && len(r.words) > 0 {
+		r.words[len(r.words)-1] += fields[0]
+		fields = fields[1:]
+	}
+	r.words = append(r.words, fields...)
+	last, _ := utf8.DecodeLastRuneInString(s)
+	r.space = unicode.IsSpace(last)
+}
+
+// glue appends s to the last word without a space.
+func (r *textRenderer) glue(s string) {
+	if len(r.words) == 0 {
+		r.words = append(r.words, s)
+	} else {
+		r.words[len(r.words)-1] += s
+	}
+	r.space = false
+}
+
+func (r *textRenderer) preformatted(s string) {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			r.flushPreformatted()
+		}
+		if len(r.words) == 0 {
+			r.words = append(r.words, line)
+		} else {
+			r.words[0] += line
+		}
+	}
+}
+
+func (r *textRenderer) flushPreformatted() {
+	line := ""
+	if len(r.words) > 0 {
+		line = r.words[0]
+	}
+	r.writeLine(r.firstPrefix() + line)
+	r.words = nil
+}
+
+// block ends the current paragraph and asks for a blank line before the
+// next one.
+func (r *textRenderer) block() {
+	r.flush()
+	r.gap = true
+}
+
+// flush writes the current paragraph wrapped to the width.
+func (r *textRenderer) flush() {
+	if r.pre > 0 {
+		if len(r.words) > 0 {
+			r.flushPreformatted()
+		}
+		return
+	}
+	if len(r.words) == 0 {
+		return
+	}
+
+	prefix := r.firstPrefix()
+	line := prefix
+	lineLen := utf8.RuneCountInString(prefix)
+	empty := true
+	for _, word := range r.words {
+		wordLen := utf8.RuneCountInString(word)
+		if !empty && lineLen+1+wordLen > r.width {
+			r.writeLine(line)
+			line = r.indent
+			lineLen = utf8.RuneCountInString(r.indent)
+			empty = true
+		}
+		if !empty {
+			line += " "
+			lineLen++
+		}
+		line += word
+		lineLen += wordLen
+		empty = false
+	}
+	r.writeLine(line)
+	r.words = nil
+	r.space = false
+}
+
+func (r *textRenderer) firstPrefix() string {
+	if r.bullet != "" {
+		bullet := r.bullet
+		r.bullet = ""
+		return bullet
+	}
+	return r.indent
+}
+
+func (r *textRenderer) writeLine(line string) {
+	if r.gap && r.out.Len() > 0 {
+		r.out.WriteString("\n")
+	}
+	r.gap = false
+	r.out.WriteString(strings.TrimRight(line, " "))
+	r.out.WriteString("\n")
+}
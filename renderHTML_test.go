@@ -0,0 +1,87 @@
+package main
+
+import "testing"
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		width int
+		want  string
+	}{
+		{
+			name:  "plain text",
+			input: "just   some\ntext",
+			width: 80,
+			want:  "just some text",
+		},
+		{
+			name:  "paragraphs",
+			input: "<p>first</p><p>second <b>bold</b>ly</p>",
+			width: 80,
+			want:  "first\n\nsecond boldly",
+		},
+		{
+			name:  "wraps to width",
+			input: "<p>the quick brown fox jumps over the lazy dog</p>",
+			width: 20,
+			want:  "the quick brown fox\njumps over the lazy\ndog",
+		},
+		{
+			name:  "lists",
+			input: "<p>intro</p><ul><li>one</li><li>two<ol><li>a</li><li>b</li></ol></li></ul>",
+			width: 80,
+			want:  "intro\n\n• one\n• two\n  1. a\n  2. b",
+		},
+		{
+			name:  "list items wrap with hanging indent",
+			input: "<ul><li>the quick brown fox jumps</li></ul>",
+			width: 20,
+			want:  "• the quick brown\n  fox jumps",
+		},
+		{
+			name:  "links as footnotes",
+			input: `<p>read <a href="https://a.example/">this</a> and <a href="https://b.example/">that</a>, or <a href="https://a.example/">this</a> again</p>`,
+			width: 80,
+			want:  "read this[1] and that[2], or this[1] again\n\n[1] https://a.example/\n[2] https://b.example/",
+		},
+		{
+			name:  "skips scripts and images without alt",
+			input: `<p>text<script>alert(1)</script><img src="x.png"></p>`,
+			width: 80,
+			want:  "text",
+		},
+		{
+			name:  "images with alt",
+			input: `<p>look <img src="x.png" alt="a cat"></p>`,
+			width: 80,
+			want:  "look [image: a cat]",
+		},
+		{
+			name:  "blockquotes",
+			input: `<blockquote><p>quoted text</p></blockquote><p>reply</p>`,
+			width: 80,
+			want:  "> quoted text\n\nreply",
+		},
+		{
+			name:  "preformatted",
+			input: "<pre>line one\n  indented</pre>",
+			width: 10,
+			want:  "line one\n  indented",
+		},
+		{
+			name:  "line breaks",
+			input: "first<br>second",
+			width: 80,
+			want:  "first\nsecond",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderHTML(tt.input, tt.width); got != tt.want {
+				t.Errorf("expected\n%q\ngot\n%q", tt.want, got)
+			}
+		})
+	}
+}
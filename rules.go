@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Filter rules hide posts in browse, digests and unread counts. A hide
+// rule drops the posts matching its pattern, an only rule the posts not
+// matching it. Patterns are case insensitive regular expressions matched
+// against the title, or the title, description and content of a post.
+func handlerRule(s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return errors.New("usage: rule add [--feed <feed>] [--in title|text] hide|only <regex> | rule list | rule rm <id>")
+	}
+
+	switch cmd.args[0] {
+	case "add":
+		return addRule(s, cmd.args[1:], user)
+	case "list":
+		return listRules(s, user)
+	case "rm":
+		if len(cmd.args) < 2 {
+			return errors.New("usage: rule rm <id>")
+		}
+		for _, ref := range cmd.args[1:] {
+			id, err := uuid.Parse(ref)
+			if err != nil {
+				return fmt.Errorf("invalid rule id %s", ref)
+			}
+			n, err := s.db.DeleteFilterRule(context.Background(), database.DeleteFilterRuleParams{
+				ID:     id,
+				UserID: user.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("couldn't remove rule %s: %w", ref, err)
+			}
+			if n == 0 {
+				return fmt.Errorf("couldn't find rule %s", ref)
+			}
+			fmt.Printf("removed rule %s\n", id)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown rule command %s, please use add, list or rm", cmd.args[0])
+}
+
+func addRule(s *state, args []string, user database.User) error {
+	fs := newFlagSet("rule add")
+	feedRef := fs.String("feed", "", "only apply the rule to this followed feed")
+	field := fs.String("in", "title", "match the title or the whole text of the posts")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return errors.New("usage: rule add [--feed <feed>] [--in title|text] hide|only <regex>")
+	}
+	action, pattern := args[0], args[1]
+	if action != "hide" && action != "only" {
+		return fmt.Errorf("unknown rule %s, please use hide or only", action)
+	}
+	if *field != "title" && *field != "text" {
+		return fmt.Errorf("unknown field %s, please use title or text", *field)
+	}
+	// the rules are matched by postgres, a pattern go accepts might break
+	// every query of the user
+	err = s.db.CheckRegexp(context.Background(), pattern)
+	if err != nil {
+		return fmt.Errorf("invalid regex %s: %w", pattern, err)
+	}
+
+	var followID uuid.NullUUID
+	scope := "all feeds"
+	if *feedRef != "" {
+		feed, follow, err := resolveFollow(s, user, *feedRef)
+		if err != nil {
+			return err
+		}
+		followID = uuid.NullUUID{UUID: follow.ID, Valid: true}
+		scope = feed.Name
+	}
+
+	rule, err := s.db.CreateFilterRule(context.Background(), database.CreateFilterRuleParams{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now().UTC(),
+		UserID:       user.ID,
+		FeedFollowID: followID,
+		Action:       action,
+		Field:        *field,
+		Pattern:      pattern,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create rule: %w", err)
+	}
+	fmt.Printf("added rule %s: %s\n", rule.ID, describeRule(rule.Action, rule.Field, rule.Pattern, scope))
+	return nil
+}
+
+func listRules(s *state, user database.User) error {
+	rules, err := s.db.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get rules: %w", err)
+	}
+	if len(rules) == 0 {
+		fmt.Println("no rules")
+		return nil
+	}
+	for _, rule := range rules {
+		scope := "all feeds"
+		if rule.FeedName.Valid {
+			scope = rule.FeedName.String
+		}
+		fmt.Printf("%s: %s\n", rule.ID, describeRule(rule.Action, rule.Field, rule.Pattern, scope))
+	}
+	return nil
+}
+
+func describeRule(action, field, pattern, scope string) string {
+	if action == "hide" {
+		return fmt.Sprintf("hide posts of %s whose %s matches %s", scope, field, pattern)
+	}
+	return fmt.Sprintf("only show posts of %s whose %s matches %s", scope, field, pattern)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func seedRule(q *fakeQuerier, user database.User, follow *database.FeedFollow, action, field, pattern string) database.FilterRule {
+	rule := database.FilterRule{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		Action:    action,
+		Field:     field,
+		Pattern:   pattern,
+	}
+	if follow != nil {
+		rule.FeedFollowID = uuid.NullUUID{UUID: follow.ID, Valid: true}
+	}
+	q.rules = append(q.rules, rule)
+	return rule
+}
+
+func TestHandlerRule(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		seedFeed(q, alice, "news", "https://news.example.com/rss")
+		seedFollow(q, alice, blog)
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerRule), []handlerTest{
+		{
+			name:        "usage",
+			currentUser: "alice",
+			setup:       setup,
+			wantErr:     "usage: rule add",
+		},
+		{
+			name:        "add global rule",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "hide", "^sponsored"},
+			wantOut:     []string{"hide posts of all feeds whose title matches ^sponsored"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.rules) != 1 || q.rules[0].FeedFollowID.Valid || q.rules[0].Field != "title" {
+					t.Errorf("unexpected rules %v", q.rules)
+				}
+			},
+		},
+		{
+			name:        "add feed rule",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "--feed", "blog", "--in", "text", "only", "golang"},
+			wantOut:     []string{"only show posts of blog whose text matches golang"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.rules) != 1 || q.rules[0].FeedFollowID.UUID != q.follows[0].ID {
+					t.Errorf("unexpected rules %v", q.rules)
+				}
+			},
+		},
+		{
+			name:        "feed not followed",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "--feed", "news", "hide", "x"},
+			wantErr:     "alice doesn't follow news",
+		},
+		{
+			name:        "invalid regex",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "hide", "(unclosed"},
+			wantErr:     "invalid regex (unclosed",
+		},
+		{
+			name:        "regex postgres doesn't accept",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "hide", `(?P<n>x)`},
+			wantErr:     "invalid regex (?P<n>x)",
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.rules) != 0 {
+					t.Errorf("expected no rule to be stored, got %v", q.rules)
+				}
+			},
+		},
+		{
+			name:        "unknown action",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "show", "x"},
+			wantErr:     "unknown rule show",
+		},
+		{
+			name:        "list",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				bob := seedUser(q, "bob")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				follow := seedFollow(q, alice, blog)
+				seedRule(q, alice, nil, "hide", "title", "sponsored")
+				seedRule(q, alice, &follow, "only", "text", "golang")
+				seedRule(q, bob, nil, "hide", "title", "bobs rule")
+			},
+			args:    []string{"list"},
+			wantOut: []string{"hide posts of all feeds whose title matches sponsored", "only show posts of blog whose text matches golang"},
+			notOut:  []string{"bobs rule"},
+		},
+		{
+			name:        "list without rules",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"list"},
+			wantOut:     []string{"no rules"},
+		},
+		{
+			name:        "rm",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				q.rules = append(q.rules, database.FilterRule{ID: uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), UserID: alice.ID, Action: "hide", Field: "title", Pattern: "x"})
+			},
+			args:    []string{"rm", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+			wantOut: []string{"removed rule 6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.rules) != 0 {
+					t.Errorf("expected no rules, got %v", q.rules)
+				}
+			},
+		},
+		{
+			name:        "rm rule of another user",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				seedUser(q, "alice")
+				bob := seedUser(q, "bob")
+				q.rules = append(q.rules, database.FilterRule{ID: uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), UserID: bob.ID, Action: "hide", Field: "title", Pattern: "x"})
+			},
+			args:    []string{"rm", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+			wantErr: "couldn't find rule",
+		},
+	})
+}
+
+func TestRulesFilterPosts(t *testing.T) {
+	now := time.Now().UTC()
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+		seedFollow(q, alice, blog)
+		newsFollow := seedFollow(q, alice, news)
+		seedPost(q, blog, "Sponsored: buy this", "https://example.com/1", now.Add(-3*time.Hour))
+		seedPost(q, blog, "a blog post", "https://example.com/2", now.Add(-2*time.Hour))
+		seedPost(q, news, "golang released", "https://news.example.com/1", now.Add(-2*time.Hour))
+		seedPost(q, news, "sports results", "https://news.example.com/2", now.Add(-time.Hour))
+		seedRule(q, alice, nil, "hide", "title", "^sponsored")
+		seedRule(q, alice, &newsFollow, "only", "title", "go(lang)?")
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerBrowse), []handlerTest{
+		{
+			name:        "browse",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"10"},
+			wantOut:     []string{"title: a blog post", "title: golang released"},
+			notOut:      []string{"Sponsored", "sports results"},
+		},
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		s, q := newTestState(t)
+		setup(q)
+		d, err := buildDigest(s, q.users[0], now.Add(-24*time.Hour), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, feed := range d.Feeds {
+			for _, post := range feed.Posts {
+				titles = append(titles, post.Title)
+			}
+		}
+		if len(titles) != 2 || titles[0] != "a blog post" || titles[1] != "golang released" {
+			t.Errorf("unexpected digest posts %v", titles)
+		}
+	})
+
+	t.Run("unread counts", func(t *testing.T) {
+		_, q := newTestState(t)
+		setup(q)
+		counts, err := q.GetUnreadCountsForUser(context.Background(), q.users[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, count := range counts {
+			if count.Unread != 1 {
+				t.Errorf("expected 1 unread post in %s, got %d", count.Name, count.Unread)
+			}
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// elements that are kept along with the attributes allowed on them,
+// everything not listed here is unwrapped and only its content is kept
+var allowedElements = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: nil,
+	atom.Br:         nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Ins:        nil,
+	atom.Kbd:        nil,
+	atom.Li:         nil,
+	atom.Ol:         nil,
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          nil,
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// elements that are dropped together with everything inside them
+var droppedElements = map[atom.Atom]bool{
+	atom.Base:     true,
+	atom.Button:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Head:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Link:     true,
+	atom.Math:     true,
+	atom.Meta:     true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Title:    true,
+}
+
+// sanitizeHTML reduces an HTML fragment to an allowlist of formatting
+// elements and attributes so it is safe to serve again.
+func sanitizeHTML(s string) string {
+	nodes, err := parseHTMLFragment(s)
+	if err != nil {
+		return html.EscapeString(s)
+	}
+
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		for _, clean := range sanitizeNode(node) {
+			html.Render(&buf, clean)
+		}
+	}
+	return buf.String()
+}
+
+func parseHTMLFragment(s string) ([]*html.Node, error) {
+	return html.ParseFragment(strings.NewReader(s), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+	})
+}
+
+// sanitizeNode returns the sanitized copies of n, more than one if n had
+// to be unwrapped and none if it was dropped.
+func sanitizeNode(n *html.Node) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: n.Data}}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	if droppedElements[n.DataAtom] {
+		return nil
+	}
+
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, sanitizeNode(c)...)
+	}
+
+	allowedAttrs, ok := allowedElements[n.DataAtom]
+	if !ok {
+		return children
+	}
+
+	clean := &html.Node{
+		Type:     html.ElementNode,
+		Data:     n.Data,
+		DataAtom: n.DataAtom,
+	}
+	for _, attr := range n.Attr {
+		if attr.Namespace != "" || !slices.Contains(allowedAttrs, attr.Key) {
+			continue
+		}
+		if (attr.Key == "href" || attr.Key == "src") && !safeURL(attr.Val) {
+			continue
+		}
+		clean.Attr = append(clean.Attr, html.Attribute{Key: attr.Key, Val: attr.Val})
+	}
+	if n.DataAtom == atom.Img && !hasAttr(clean, "src") {
+		return nil
+	}
+	for _, child := range children {
+		clean.AppendChild(child)
+	}
+	return []*html.Node{clean}
+}
+
+// safeURL reports whether u is relative or uses a scheme that can't run
+// code in a browser.
+func safeURL(u string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	return attrValue(n, key) != ""
+}
+
+func attrValue(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "plain text",
+			input: "just text & more",
+			want:  "just text &amp; more",
+		},
+		{
+			name:  "keeps formatting",
+			input: `<p>Hello <strong>world</strong></p><ul><li>one</li></ul>`,
+			want:  `<p>Hello <strong>world</strong></p><ul><li>one</li></ul>`,
+		},
+		{
+			name:  "drops scripts with content",
+			input: `<p>before</p><script>alert("x")</script><style>p{}</style><p>after</p>`,
+			want:  `<p>before</p><p>after</p>`,
+		},
+		{
+			name:  "drops event handlers and styles",
+			input: `<p onclick="evil()" style="color:red" class="x">text</p>`,
+			want:  `<p>text</p>`,
+		},
+		{
+			name:  "drops javascript links",
+			input: `<a href="javascript:alert(1)" title="t">click</a> <a href="https://example.com/">ok</a>`,
+			want:  `<a title="t">click</a> <a href="https://example.com/">ok</a>`,
+		},
+		{
+			name:  "unwraps unknown elements",
+			input: `<section><font color="red">text</font></section>`,
+			want:  `text`,
+		},
+		{
+			name:  "images need a safe source",
+			input: `<img src="https://example.com/a.png" alt="a" onerror="evil()"><img src="data:image/png;base64,AAAA">`,
+			want:  `<img src="https://example.com/a.png" alt="a"/>`,
+		},
+		{
+			name:  "escaped markup stays text",
+			input: `&lt;script&gt;alert(1)&lt;/script&gt;`,
+			want:  `&lt;script&gt;alert(1)&lt;/script&gt;`,
+		},
+		{
+			name:  "closes unbalanced markup",
+			input: `<p><em>open`,
+			want:  `<p><em>open</em></p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeHTML(tt.input); got != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
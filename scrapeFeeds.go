@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"time"
+	"log"
 	"database/sql"
 
 	"github.com/google/uuid"
@@ -15,7 +16,12 @@ func scrapeFeeds(s* state) error {
 	if err != nil {
 		return errors.New("couldn't get next feed")
 	}
-	err = s.db.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
+	return scrapeFeed(s, feed)
+}
+
+// scrapeFeed fetches feed and stores its new posts.
+func scrapeFeed(s *state, feed database.Feed) error {
+	err := s.db.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
 		LastFetchedAt: sql.NullTime{
 			Time: time.Now().UTC(), 
 			Valid: true,
@@ -28,9 +34,78 @@ func scrapeFeeds(s* state) error {
 	}
 	rssFeed, err := fetchFeed(context.Background(), feed.Url)
 	if err != nil {
+		recordErr := s.db.SetFeedFetchError(context.Background(), database.SetFeedFetchErrorParams{
+			LastError: sql.NullString{
+				String: err.Error(),
+				Valid: true,
+			},
+			UpdatedAt: time.Now().UTC(),
+			ID: feed.ID,
+		})
+		if recordErr != nil {
+			log.Printf("couldn't store the fetch error of %s: %v", feed.Name, recordErr)
+		}
 		return err
 	}
+	image := rssFeed.imageURL()
+	err = s.db.SetFeedFetchSuccess(context.Background(), database.SetFeedFetchSuccessParams{
+		Title: sql.NullString{
+			String: rssFeed.Channel.Title,
+			Valid: rssFeed.Channel.Title != "",
+		},
+		SiteUrl: sql.NullString{
+			String: rssFeed.Channel.Link,
+			Valid: rssFeed.Channel.Link != "",
+		},
+		Description: sql.NullString{
+			String: rssFeed.Channel.Description,
+			Valid: rssFeed.Channel.Description != "",
+		},
+		Language: sql.NullString{
+			String: rssFeed.Channel.Language,
+			Valid: rssFeed.Channel.Language != "",
+		},
+		ImageUrl: sql.NullString{
+			String: image,
+			Valid: image != "",
+		},
+		Generator: sql.NullString{
+			String: rssFeed.Channel.Generator,
+			Valid: rssFeed.Channel.Generator != "",
+		},
+		LastSuccessAt: sql.NullTime{
+			Time: time.Now().UTC(),
+			Valid: true,
+		},
+		UpdatedAt: time.Now().UTC(),
+		ID: feed.ID,
+	})
+	if err != nil {
+		return errors.New("couldn't store feed fetch")
+	}
 
+	hub, topic := rssFeed.hubLinks()
+	err = s.db.SetFeedHub(context.Background(), database.SetFeedHubParams{
+		HubUrl: sql.NullString{
+			String: hub,
+			Valid: hub != "",
+		},
+		TopicUrl: sql.NullString{
+			String: topic,
+			Valid: topic != "",
+		},
+		ID: feed.ID,
+	})
+	if err != nil {
+		return errors.New("couldn't store feed hub")
+	}
+
+	return storePosts(s, feed, rssFeed)
+}
+
+// storePosts saves the items of a fetched or pushed feed as posts of feed.
+func storePosts(s *state, feed database.Feed, rssFeed *RSSFeed) error {
+	var err error
 	for _, item := range rssFeed.Channel.Item {
 
 		title := sql.NullString{}
@@ -41,10 +116,11 @@ func scrapeFeeds(s* state) error {
 			title.Valid = true
 		}
 		description := sql.NullString{}
-		if item.Description == "" {
+		sanitized := sanitizeHTML(item.Description)
+		if sanitized == "" {
 			description.Valid = false
 		} else {
-			description.String = item.Description
+			description.String = sanitized
 			description.Valid = true
 		}
 
@@ -73,12 +149,13 @@ func scrapeFeeds(s* state) error {
 			publishedAt.Valid = true
 		}
 
-		err = s.db.CreatePost(context.Background(), database.CreatePostParams{
+		link := canonicalPostURL(item.Link)
+		created, err := s.db.CreatePost(context.Background(), database.CreatePostParams{
 			ID:			uuid.New(),
 			CreatedAt: 	time.Now().UTC(),
 			UpdatedAt:  time.Now().UTC(),
 			Title:      title,
-			Url:		item.Link,
+			Url:		link,
 			Description: description,
 			PublishedAt:	publishedAt,
 			FeedID:		feed.ID,
@@ -86,6 +163,30 @@ func scrapeFeeds(s* state) error {
 		if err != nil {
 			return err
 		}
+
+		if created > 0 && feed.Fulltext && link != "" {
+			err = storeArticle(s, link)
+			if err != nil {
+				log.Printf("couldn't get full article %s: %v", link, err)
+			}
+		}
 	}
 return nil
 }
+
+// storeArticle extracts the full article from the post's page and stores
+// it as the post's content.
+func storeArticle(s *state, postURL string) error {
+	content, err := fetchArticle(context.Background(), postURL)
+	if err != nil {
+		return err
+	}
+	return s.db.SetPostContent(context.Background(), database.SetPostContentParams{
+		Content: sql.NullString{
+			String: content,
+			Valid: true,
+		},
+		UpdatedAt: time.Now().UTC(),
+		Url: postURL,
+	})
+}
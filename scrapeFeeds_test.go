@@ -0,0 +1,217 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStorePostsSanitizesDescriptions(t *testing.T) {
+	s, q := newTestState(t)
+	alice := seedUser(q, "alice")
+	feed := seedFeed(q, alice, "blog", "https://example.com/rss")
+
+	rssFeed, err := parseFeed([]byte(`<rss><channel>
+<item>
+  <title>Tom &amp; Jerry</title>
+  <link>https://example.com/1</link>
+  <description><![CDATA[<p onclick="x()">hi</p><script>alert(1)</script>]]></description>
+</item>
+<item>
+  <title>escaped</title>
+  <link>https://example.com/2</link>
+  <description>&lt;p&gt;a &amp;lt;b&amp;gt; tag&lt;/p&gt;</description>
+</item>
+</channel></rss>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = storePosts(s, feed, rssFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(q.posts) != 2 {
+		t.Fatalf("expected 2 posts, got %d", len(q.posts))
+	}
+	if q.posts[0].Title.String != "Tom & Jerry" {
+		t.Errorf("unexpected title %q", q.posts[0].Title.String)
+	}
+	if q.posts[0].Description.String != "<p>hi</p>" {
+		t.Errorf("expected sanitized description, got %q", q.posts[0].Description.String)
+	}
+	if q.posts[1].Description.String != "<p>a &lt;b&gt; tag</p>" {
+		t.Errorf("expected escaped markup to stay text, got %q", q.posts[1].Description.String)
+	}
+}
+
+func TestStorePostsFulltext(t *testing.T) {
+	page, err := os.ReadFile("testdata/articles/blog.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(page)
+	}))
+	defer server.Close()
+	useHostLimiter(t, 0)
+
+	s, q := newTestState(t)
+	alice := seedUser(q, "alice")
+	feed := seedFeed(q, alice, "blog", "https://example.com/rss")
+
+	rssFeed := &RSSFeed{}
+	rssFeed.Channel.Item = []RSSItem{
+		{Title: "existing", Link: server.URL + "/existing", Description: "summary"},
+		{Title: "new", Link: server.URL + "/new", Description: "summary"},
+		{Title: "gone", Link: server.URL + "/gone", Description: "summary"},
+	}
+	seedPost(q, feed, "existing", server.URL+"/existing", time.Now())
+
+	err = storePosts(s, feed, rssFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no articles to be fetched without fulltext, got %d requests", requests)
+	}
+
+	q.posts = q.posts[:1]
+	feed.Fulltext = true
+	err = storePosts(s, feed, rssFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("expected only the new posts to be fetched, got %d requests", requests)
+	}
+	for _, post := range q.posts {
+		switch post.Title.String {
+		case "new":
+			if !strings.Contains(post.Content.String, "test your backups") {
+				t.Errorf("expected extracted article, got %q", post.Content.String)
+			}
+			if post.Description.String != "summary" {
+				t.Errorf("expected summary to be kept, got %q", post.Description.String)
+			}
+		default:
+			if post.Content.Valid {
+				t.Errorf("expected no content for %s, got %q", post.Title.String, post.Content.String)
+			}
+		}
+	}
+}
+
+func TestScrapeFeedRecordsFetchState(t *testing.T) {
+	useHostLimiter(t, 0)
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`<rss><channel><title>Example &amp; Co</title>
+<link>https://example.com/</link>
+<description>all about examples</description>
+<language>en-us</language>
+<generator>Hugo</generator>
+<image><url>/logo.png</url></image>
+<item><title>post</title><link>https://example.com/1</link></item>
+</channel></rss>`))
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t)
+	alice := seedUser(q, "alice")
+	feed := seedFeed(q, alice, "blog", server.URL)
+
+	err := scrapeFeed(s, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := q.feeds[0]
+	if got.Title.String != "Example & Co" || got.Description.String != "all about examples" {
+		t.Errorf("expected the channel title and description, got %q and %q", got.Title.String, got.Description.String)
+	}
+	if !got.LastSuccessAt.Valid || got.LastError.Valid {
+		t.Errorf("expected a successful fetch, got %v and %v", got.LastSuccessAt, got.LastError)
+	}
+	if got.SiteUrl.String != "https://example.com/" || got.Language.String != "en-us" || got.Generator.String != "Hugo" || got.ImageUrl.String != "https://example.com/logo.png" {
+		t.Errorf("unexpected channel metadata %v, %v, %v, %v", got.SiteUrl, got.Language, got.Generator, got.ImageUrl)
+	}
+
+	failing = true
+	err = scrapeFeed(s, q.feeds[0])
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	got = q.feeds[0]
+	if !got.LastError.Valid || !strings.Contains(got.LastError.String, "500") {
+		t.Errorf("expected the error to be stored, got %v", got.LastError)
+	}
+	if got.Title.String != "Example & Co" || !got.LastSuccessAt.Valid {
+		t.Errorf("expected the last successful fetch to be kept, got %v", got)
+	}
+}
+
+func TestRSSFeedImageURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		want    string
+	}{
+		{
+			name:    "image",
+			channel: `<link>https://example.com/blog</link><image><url>https://cdn.example.com/logo.png</url></image>`,
+			want:    "https://cdn.example.com/logo.png",
+		},
+		{
+			name:    "relative image",
+			channel: `<link>https://example.com/blog/</link><image><url>logo.png</url></image>`,
+			want:    "https://example.com/blog/logo.png",
+		},
+		{
+			name: "podcast artwork",
+			channel: `<link>https://example.com/</link>
+<itunes:image xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" href="https://example.com/cover.jpg"/>`,
+			want: "https://example.com/cover.jpg",
+		},
+		{
+			name: "image before podcast artwork",
+			channel: `<image><url>https://example.com/logo.png</url></image>
+<itunes:image xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" href="https://example.com/cover.jpg"/>`,
+			want: "https://example.com/logo.png",
+		},
+		{
+			name:    "favicon",
+			channel: `<link>https://example.com/blog</link>`,
+			want:    "https://example.com/favicon.ico",
+		},
+		{
+			name:    "nothing",
+			channel: `<link>not a site</link>`,
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte("<rss><channel>" + tt.channel + "</channel></rss>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := feed.imageURL(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/arglp/gator/internal/database"
+	"golang.org/x/term"
+)
+
+const (
+	highlightStart = "[["
+	highlightStop  = "]]"
+)
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+	fs := newFlagSet(cmd.name)
+	all := fs.Bool("all", false, "search the posts of all feeds")
+	limit := fs.Int("limit", 10, "maximum number of results")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return errors.New("please provide a search query")
+	}
+
+	query, err := parseSearchQuery(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+
+	results, err := s.db.SearchPosts(context.Background(), database.SearchPostsParams{
+		Query:    query,
+		AllFeeds: *all,
+		UserID:   user.ID,
+		Limit:    int32(*limit),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't search posts: %w", err)
+	}
+
+	if s.output != outputText {
+		unmark := strings.NewReplacer(highlightStart, "", highlightStop, "")
+		var records []searchRecord
+		for _, result := range results {
+			records = append(records, searchRecord{
+				ID:          result.ID,
+				PublishedAt: nullTime(result.PublishedAt.Time, result.PublishedAt.Valid),
+				FeedName:    result.FeedName,
+				Title:       unmark.Replace(result.Title),
+				URL:         result.Url,
+				Rank:        result.Rank,
+				Snippet:     unmark.Replace(snippetText(result.Snippet)),
+			})
+		}
+		return writeRecords(os.Stdout, s.output, records)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("no posts found")
+		return nil
+	}
+
+	start, stop := "*", "*"
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		start, stop = "\033[1m", "\033[0m"
+	}
+	highlight := strings.NewReplacer(highlightStart, start, highlightStop, stop)
+
+	for _, result := range results {
+		fmt.Printf("title: %s\n", highlight.Replace(result.Title))
+		fmt.Printf("id: %s\n", result.ID)
+		fmt.Printf("link: %s\n", result.Url)
+		fmt.Printf("feed: %s\n", result.FeedName)
+		if result.PublishedAt.Valid {
+			fmt.Printf("published: %v\n", result.PublishedAt.Time)
+		}
+		if snippet := snippetText(result.Snippet); snippet != "" {
+			fmt.Printf("... %s ...\n", highlight.Replace(snippet))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// parseSearchQuery turns a search query into a tsquery. Words have to
+// match all, "quoted words" match a phrase, word* matches a prefix, -word
+// excludes posts and OR between words matches either of them.
+func parseSearchQuery(query string) (string, error) {
+	var terms []string
+	or := false
+	for _, token := range splitSearchQuery(query) {
+		if token == "OR" {
+			or = len(terms) > 0
+			continue
+		}
+
+		negate := false
+		if rest, ok := strings.CutPrefix(token, "-"); ok && rest != "" {
+			negate = true
+			token = rest
+		}
+		prefix := false
+		if rest, ok := strings.CutSuffix(token, "*"); ok {
+			prefix = true
+			token = rest
+		}
+		token = strings.Trim(token, `"`)
+
+		words := strings.FieldsFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+		term := strings.Join(words, " <-> ")
+		if prefix {
+			term += ":*"
+		}
+		if len(words) > 1 {
+			term = "(" + term + ")"
+		}
+		if negate {
+			term = "!" + term
+		}
+
+		if or {
+			terms[len(terms)-1] += " | " + term
+			or = false
+			continue
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return "", errors.New("please provide a search query")
+	}
+	// OR binds tighter than the implicit AND
+	for i, term := range terms {
+		if strings.Contains(term, " | ") {
+			terms[i] = "(" + term + ")"
+		}
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// splitSearchQuery splits query at spaces outside of double quotes.
+func splitSearchQuery(query string) []string {
+	var tokens []string
+	var token strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			token.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+		default:
+			token.WriteRune(r)
+		}
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
+
+// snippetText returns the text of a snippet that was cut out of HTML.
+func snippetText(snippet string) string {
+	nodes, err := parseHTMLFragment(snippet)
+	if err != nil {
+		return strings.Join(strings.Fields(snippet), " ")
+	}
+	var texts []string
+	for _, node := range nodes {
+		texts = append(texts, textContent(node))
+	}
+	return strings.Join(strings.Fields(strings.Join(texts, " ")), " ")
+}
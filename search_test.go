@@ -0,0 +1,105 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{query: "postgres", want: "postgres"},
+		{query: "postgres index", want: "postgres & index"},
+		{query: `"full text search"`, want: "(full <-> text <-> search)"},
+		{query: "kube*", want: "kube:*"},
+		{query: "postgres -mysql", want: "postgres & !mysql"},
+		{query: "postgres OR mysql index", want: "(postgres | mysql) & index"},
+		{query: `-"release notes" go`, want: "!(release <-> notes) & go"},
+		{query: "don't panic", want: "(don <-> t) & panic"},
+		{query: "it's 'quoted' & | !", want: "(it <-> s) & quoted"},
+		{query: "OR", wantErr: true},
+		{query: "& !", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseSearchQuery(tt.query)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSnippetText(t *testing.T) {
+	got := snippetText("ends here</p>\n<p>a [[postgres]] <b>index</b> is")
+	want := "ends here a [[postgres]] index is"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestHandlerSearch(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		other := seedFeed(q, alice, "other", "https://other.example.com/rss")
+		seedFollow(q, alice, blog)
+		seedPost(q, blog, "postgres indexes", "https://example.com/1", time.Now())
+		seedPost(q, other, "postgres elsewhere", "https://other.example.com/1", time.Now())
+		q.posts[0].Description = sql.NullString{String: "<p>a gin index speeds up postgres search</p>", Valid: true}
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerSearch), []handlerTest{
+		{
+			name:        "missing query",
+			currentUser: "alice",
+			setup:       setup,
+			wantErr:     "please provide a search query",
+		},
+		{
+			name:        "followed feeds",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"postgres"},
+			wantOut:     []string{"title: *postgres* indexes", "feed: blog", "... a gin index speeds up postgres search ..."},
+			notOut:      []string{"elsewhere"},
+		},
+		{
+			name:        "all feeds",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"--all", "postgres"},
+			wantOut:     []string{"title: *postgres* indexes", "title: *postgres* elsewhere"},
+		},
+		{
+			name:        "structured output without highlights",
+			currentUser: "alice",
+			output:      outputNDJSON,
+			setup:       setup,
+			args:        []string{"postgres"},
+			wantOut:     []string{`"title":"postgres indexes"`, `"snippet":"a gin index speeds up postgres search"`},
+			notOut:      []string{"[[", "*postgres*"},
+		},
+		{
+			name:        "no results",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"mysql"},
+			wantOut:     []string{"no posts found"},
+		},
+	})
+}
@@ -0,0 +1,35 @@
+-- name: SetEmailDigest :one
+INSERT INTO email_digests (user_id, created_at, updated_at, email, schedule, format)
+Values (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+) ON CONFLICT (user_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    email = EXCLUDED.email,
+    schedule = EXCLUDED.schedule,
+    format = EXCLUDED.format
+RETURNING *;
+
+-- name: GetEmailDigest :one
+SELECT *
+FROM email_digests
+WHERE user_id = $1;
+
+-- name: DeleteEmailDigest :exec
+DELETE FROM email_digests
+WHERE user_id = $1;
+
+-- name: GetEmailDigests :many
+SELECT email_digests.*, users.name AS user_name
+FROM email_digests
+INNER JOIN users ON email_digests.user_id = users.id
+ORDER BY email_digests.created_at;
+
+-- name: MarkEmailDigestSent :exec
+UPDATE email_digests
+SET last_sent_at = $1, updated_at = $2
+WHERE user_id = $3;
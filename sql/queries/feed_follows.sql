@@ -21,15 +21,42 @@ ON inserted_feed_follow.feed_id = feeds.id;
 
 -- name: GetFeedFollowsForUser :many
 
-SELECT feeds.name AS feed_name, users.name AS user_name
+SELECT
+    feed_follows.*,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    feeds.title AS feed_title,
+    feeds.site_url AS feed_site_url,
+    feeds.description AS feed_description,
+    feeds.language AS feed_language,
+    feeds.image_url AS feed_image_url,
+    feeds.generator AS feed_generator,
+    users.name AS user_name
 FROM feed_follows
 INNER JOIN users
 ON feed_follows.user_id = users.id
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
-WHERE feed_follows.user_id = $1;
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.created_at;
 
 -- name: DeleteFeedFollow :exec
 
 DELETE FROM feed_follows
-WHERE user_id = $1 AND feed_id = $2;
\ No newline at end of file
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: GetFeedFollow :one
+SELECT *
+FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: MoveFeedFollows :execrows
+-- Users following both feeds keep their follow of the feed moved to.
+UPDATE feed_follows
+SET feed_id = @to_feed_id, updated_at = @updated_at
+WHERE feed_follows.feed_id = @from_feed_id
+AND feed_follows.user_id NOT IN (
+    SELECT followers.user_id
+    FROM feed_follows AS followers
+    WHERE followers.feed_id = @to_feed_id
+);
@@ -11,16 +11,34 @@ Values (
 RETURNING *;
 
 -- name: GetFeeds :many
-SELECT feeds.name, feeds.url, users.name AS user_name
+SELECT feeds.*, users.name AS user_name
 FROM feeds
 INNER JOIN users
-ON feeds.user_id = users.id;
+ON feeds.user_id = users.id
+ORDER BY feeds.created_at;
 
 -- name: GetFeed :one
 SELECT *
 FROM feeds
 WHERE url = $1;
 
+-- name: GetFeedsByName :many
+SELECT *
+FROM feeds
+WHERE name = $1
+ORDER BY created_at;
+
+-- name: GetFeedsByNamePrefix :many
+SELECT *
+FROM feeds
+WHERE name ILIKE @pattern
+ORDER BY name, created_at;
+
+-- name: GetFeedByID :one
+SELECT *
+FROM feeds
+WHERE id = $1;
+
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = $1, updated_at = $2
@@ -30,4 +48,162 @@ WHERE id = $3;
 SELECT *
 FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: SetFeedHub :exec
+UPDATE feeds
+SET hub_url = $1, topic_url = $2
+WHERE id = $3;
+
+-- name: GetFeedsWithHub :many
+SELECT *
+FROM feeds
+WHERE hub_url IS NOT NULL AND topic_url IS NOT NULL;
+
+-- name: SetFeedFulltext :exec
+UPDATE feeds
+SET fulltext = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: RenameFeed :exec
+UPDATE feeds
+SET name = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: SetFeedURL :exec
+-- The hub of the old url doesn't apply to the new one, the feed is
+-- fetched again as soon as possible to find its hub.
+UPDATE feeds
+SET url = $1, updated_at = $2, hub_url = NULL, topic_url = NULL, last_fetched_at = NULL
+WHERE id = $3;
+
+-- name: GetFeedCounts :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = @feed_id) AS followers,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = @feed_id) AS posts;
+
+-- name: GetFeedStats :one
+-- Posts without a publication date count from the time they were stored.
+-- The first and last posts are joined as rows, so that their dates are
+-- NULL if the feed has no posts.
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id) AS followers,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = feeds.id) AS posts,
+    (SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id = feeds.id
+        AND COALESCE(posts.published_at, posts.created_at) >= @since::timestamp) AS posts_since,
+    first_post.published_at AS first_post_published_at,
+    first_post.created_at AS first_post_created_at,
+    last_post.published_at AS last_post_published_at,
+    last_post.created_at AS last_post_created_at
+FROM feeds
+LEFT JOIN posts AS first_post
+ON first_post.id = (
+    SELECT posts.id
+    FROM posts
+    WHERE posts.feed_id = feeds.id
+    ORDER BY COALESCE(posts.published_at, posts.created_at), posts.id
+    LIMIT 1
+)
+LEFT JOIN posts AS last_post
+ON last_post.id = (
+    SELECT posts.id
+    FROM posts
+    WHERE posts.feed_id = feeds.id
+    ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id DESC
+    LIMIT 1
+)
+WHERE feeds.id = @feed_id;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: DeleteFeeds :exec
+DELETE FROM feeds;
+
+-- name: ChownFeed :exec
+UPDATE feeds
+SET user_id = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: ReassignFeedsOfUser :many
+-- The feeds of a user go to the user who followed them first, feeds
+-- nobody else follows to the system user.
+WITH reassigned_feeds AS (
+UPDATE feeds
+SET user_id = COALESCE((
+        SELECT feed_follows.user_id
+        FROM feed_follows
+        WHERE feed_follows.feed_id = feeds.id
+        AND feed_follows.user_id <> feeds.user_id
+        ORDER BY feed_follows.created_at
+        LIMIT 1
+    ), @system_user_id::uuid),
+    updated_at = @updated_at
+WHERE feeds.user_id = @user_id
+RETURNING *
+) SELECT
+    reassigned_feeds.*,
+    users.name AS user_name
+FROM reassigned_feeds
+INNER JOIN users
+ON reassigned_feeds.user_id = users.id
+ORDER BY reassigned_feeds.name;
+
+-- name: SetFeedFetchSuccess :exec
+-- The channel metadata is replaced on every successful fetch, elements the
+-- feed stopped sending are cleared.
+UPDATE feeds
+SET title = @title,
+    site_url = @site_url,
+    description = @description,
+    language = @language,
+    image_url = @image_url,
+    generator = @generator,
+    last_success_at = @last_success_at,
+    last_error = NULL,
+    updated_at = @updated_at
+WHERE id = @id;
+
+-- name: SetFeedFetchError :exec
+UPDATE feeds
+SET last_error = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: SearchFeeds :many
+-- search is an ILIKE pattern matched against the name, url and channel
+-- description. Feeds followed by exclude_user_id are left out, sort is
+-- followers, recent (newest first) or name, feeds are ordered by
+-- creation otherwise. A NULL limit returns all feeds.
+WITH follower_counts AS (
+    SELECT feed_follows.feed_id, COUNT(*) AS followers
+    FROM feed_follows
+    GROUP BY feed_follows.feed_id
+)
+SELECT
+    feeds.*,
+    users.name AS user_name,
+    COALESCE(follower_counts.followers, 0)::bigint AS followers
+FROM feeds
+INNER JOIN users
+ON feeds.user_id = users.id
+LEFT JOIN follower_counts
+ON follower_counts.feed_id = feeds.id
+WHERE (sqlc.narg('search')::text IS NULL
+    OR feeds.name ILIKE sqlc.narg('search')::text
+    OR feeds.url ILIKE sqlc.narg('search')::text
+    OR feeds.description ILIKE sqlc.narg('search')::text)
+AND (sqlc.narg('exclude_user_id')::uuid IS NULL OR NOT EXISTS (
+    SELECT 1
+    FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id = sqlc.narg('exclude_user_id')::uuid
+))
+AND COALESCE(follower_counts.followers, 0) >= @min_followers::bigint
+ORDER BY
+    CASE WHEN @sort::text = 'followers' THEN COALESCE(follower_counts.followers, 0) END DESC,
+    CASE WHEN @sort::text = 'recent' THEN feeds.created_at END DESC,
+    CASE WHEN @sort::text = 'name' THEN lower(feeds.name) END,
+    feeds.created_at
+LIMIT sqlc.narg('limit');
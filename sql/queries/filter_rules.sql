@@ -0,0 +1,30 @@
+-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, created_at, user_id, feed_follow_id, action, field, pattern)
+Values (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING *;
+
+-- name: GetFilterRulesForUser :many
+SELECT filter_rules.*, feeds.name AS feed_name
+FROM filter_rules
+LEFT JOIN feed_follows ON filter_rules.feed_follow_id = feed_follows.id
+LEFT JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE filter_rules.user_id = $1
+ORDER BY filter_rules.created_at;
+
+-- name: DeleteFilterRule :execrows
+DELETE FROM filter_rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: CheckRegexp :exec
+-- Rules are matched by postgres, whose regular expressions aren't the ones
+-- of Go, so patterns are checked by postgres before they are stored. It
+-- fails if pattern isn't a valid regular expression.
+SELECT '' ~* @pattern::text;
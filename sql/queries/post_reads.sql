@@ -0,0 +1,46 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+Values (
+    $1,
+    $2,
+    $3
+) ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, @read_at::timestamp
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = @user_id
+AND (sqlc.narg('feed_id')::uuid IS NULL OR posts.feed_id = sqlc.narg('feed_id'))
+AND (sqlc.narg('before')::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg('before'))
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: GetUnreadCountsForUser :many
+-- Posts hidden by a filter rule aren't counted, see GetPostForUser.
+SELECT
+    feeds.id,
+    feeds.name,
+    feeds.url,
+    COUNT(posts.id) FILTER (WHERE post_reads.post_id IS NULL) AS unread
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN posts ON posts.feed_id = feeds.id
+    AND NOT EXISTS (
+        SELECT 1
+        FROM filter_rules
+        WHERE filter_rules.user_id = feed_follows.user_id
+        AND (filter_rules.feed_follow_id IS NULL OR filter_rules.feed_follow_id = feed_follows.id)
+        AND (CASE WHEN filter_rules.field = 'title'
+                THEN COALESCE(posts.title, '')
+                ELSE concat_ws(' ', posts.title, posts.description, posts.content)
+            END ~* filter_rules.pattern) = (filter_rules.action = 'hide')
+    )
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id
+ORDER BY feeds.name;
@@ -0,0 +1,32 @@
+-- name: StarPost :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+Values (
+    $1,
+    $2,
+    $3
+) ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: UnstarPost :exec
+DELETE FROM post_stars
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetStarredPostsForUser :many
+SELECT
+    posts.*,
+    feeds.name AS feed_name,
+    post_reads.read_at,
+    post_stars.starred_at
+FROM post_stars
+INNER JOIN posts ON post_stars.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = post_stars.user_id
+WHERE post_stars.user_id = $1
+ORDER BY post_stars.starred_at DESC;
+
+-- name: GetStarredPostsToArchive :many
+SELECT posts.*
+FROM post_stars
+INNER JOIN posts ON post_stars.post_id = posts.id
+WHERE post_stars.user_id = $1
+AND posts.archive_path IS NULL
+ORDER BY post_stars.starred_at DESC;
@@ -1,4 +1,4 @@
--- name: CreatePost :exec
+-- name: CreatePost :execrows
 INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
 Values (
     $1,
@@ -12,10 +12,92 @@ Values (
 ) ON CONFLICT (url) DO NOTHING;
 
 -- name: GetPostForUser :many
+-- Posts are ordered by (published_at, id), posts without a publication
+-- date sort by the time they were stored. The before and after cursors
+-- select the posts older or newer than a post, posts newer than the after
+-- cursor are returned oldest first so the page ends next to the cursor.
+-- match is an ILIKE pattern, tag also selects the feeds of its nested
+-- tags like tag/subtag. Posts hidden by a filter rule of the user
+-- are left out: hide rules drop the posts matching them, only rules the
+-- posts not matching them. since and until apply to the publication
+-- date, stored_since to the time the post was stored.
 SELECT 
-    posts.*
+    posts.*,
+    feeds.name AS feed_name,
+    post_reads.read_at,
+    post_stars.starred_at
 FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-Limit $2;
\ No newline at end of file
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+LEFT JOIN post_stars ON post_stars.post_id = posts.id AND post_stars.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = @user_id
+AND (NOT @archived::bool OR posts.archive_path IS NOT NULL)
+AND (@include_read::bool OR post_reads.post_id IS NULL)
+AND (sqlc.narg('feed_id')::uuid IS NULL OR posts.feed_id = sqlc.narg('feed_id')::uuid)
+AND (sqlc.narg('since')::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg('since')::timestamp)
+AND (sqlc.narg('until')::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg('until')::timestamp)
+AND (sqlc.narg('stored_since')::timestamp IS NULL OR posts.created_at >= sqlc.narg('stored_since')::timestamp)
+AND (sqlc.narg('tag')::text IS NULL OR EXISTS (
+    SELECT 1
+    FROM tags
+    WHERE tags.feed_follow_id = feed_follows.id
+    AND (tags.name = sqlc.narg('tag')::text OR starts_with(tags.name, sqlc.narg('tag')::text || '/'))
+))
+AND (sqlc.narg('match')::text IS NULL
+    OR posts.title ILIKE sqlc.narg('match')::text
+    OR posts.description ILIKE sqlc.narg('match')::text
+    OR posts.content ILIKE sqlc.narg('match')::text)
+AND NOT EXISTS (
+    SELECT 1
+    FROM filter_rules
+    WHERE filter_rules.user_id = feed_follows.user_id
+    AND (filter_rules.feed_follow_id IS NULL OR filter_rules.feed_follow_id = feed_follows.id)
+    AND (CASE WHEN filter_rules.field = 'title'
+            THEN COALESCE(posts.title, '')
+            ELSE concat_ws(' ', posts.title, posts.description, posts.content)
+        END ~* filter_rules.pattern) = (filter_rules.action = 'hide')
+)
+AND (sqlc.narg('before_id')::uuid IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) < (sqlc.narg('before_published_at')::timestamp, sqlc.narg('before_id')::uuid))
+AND (sqlc.narg('after_id')::uuid IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) > (sqlc.narg('after_published_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY
+    CASE WHEN sqlc.narg('after_id')::uuid IS NULL THEN COALESCE(posts.published_at, posts.created_at) END DESC,
+    CASE WHEN sqlc.narg('after_id')::uuid IS NULL THEN posts.id END DESC,
+    COALESCE(posts.published_at, posts.created_at) ASC,
+    posts.id ASC
+Limit sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $1, updated_at = $2
+WHERE url = $3;
+
+-- name: GetPostsToArchive :many
+SELECT *
+FROM posts
+WHERE feed_id = ANY(@feed_ids::uuid[])
+AND archive_path IS NULL
+ORDER BY published_at DESC;
+
+-- name: SetPostArchivePath :exec
+UPDATE posts
+SET archive_path = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: GetPostByID :one
+SELECT *
+FROM posts
+WHERE id = $1;
+
+-- name: GetPostByURL :one
+SELECT *
+FROM posts
+WHERE url = $1;
+
+-- name: MovePosts :execrows
+UPDATE posts
+SET feed_id = @to_feed_id, updated_at = @updated_at
+WHERE feed_id = @from_feed_id;
@@ -0,0 +1,29 @@
+-- name: SearchPosts :many
+-- query is in to_tsquery syntax. Matches are highlighted with [[ and ]]
+-- in the headline of the title and the snippet of the body. The search
+-- vector has to be the expression of posts_search_idx for the index to be
+-- used.
+SELECT
+    posts.id,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_rank((setweight(to_tsvector('english', COALESCE(posts.title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(posts.description, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(posts.content, '')), 'C')),
+        to_tsquery('english', @query::text)) AS rank,
+    ts_headline('english', COALESCE(posts.title, ''), to_tsquery('english', @query::text),
+        'HighlightAll=true, StartSel=[[, StopSel=]]')::text AS title,
+    ts_headline('english', COALESCE(posts.content, posts.description, ''), to_tsquery('english', @query::text),
+        'MaxFragments=2, MinWords=5, MaxWords=20, StartSel=[[, StopSel=]]')::text AS snippet
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE (setweight(to_tsvector('english', COALESCE(posts.title, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(posts.description, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(posts.content, '')), 'C'))
+    @@ to_tsquery('english', @query::text)
+AND (@all_feeds::bool OR posts.feed_id IN (
+    SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = @user_id
+))
+ORDER BY rank DESC, posts.published_at DESC NULLS LAST, posts.id
+LIMIT sqlc.arg('limit');
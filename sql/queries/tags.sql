@@ -0,0 +1,19 @@
+-- name: AddTag :exec
+INSERT INTO tags (feed_follow_id, name, created_at)
+Values (
+    $1,
+    $2,
+    $3
+) ON CONFLICT (feed_follow_id, name) DO NOTHING;
+
+-- name: GetTagsForUser :many
+SELECT tags.*, feed_follows.feed_id, feeds.name AS feed_name
+FROM tags
+INNER JOIN feed_follows ON tags.feed_follow_id = feed_follows.id
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY tags.name, feeds.name;
+
+-- name: DeleteTag :execrows
+DELETE FROM tags
+WHERE feed_follow_id = $1 AND name = $2;
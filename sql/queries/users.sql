@@ -12,9 +12,29 @@ RETURNING *;
 SELECT * FROM users
 WHERE name = $1;
 
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
 -- name: GetUsers :many
 SELECT * FROM users
 ORDER BY created_at DESC;
 
 -- name: DeleteUsers :exec
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: EnsureSystemUser :one
+-- The system user owns the feeds nobody else can take over.
+INSERT INTO users (id, created_at, updated_at, name)
+Values (
+    $1,
+    $2,
+    $3,
+    $4
+) ON CONFLICT (id) DO UPDATE
+SET updated_at = users.updated_at
+RETURNING *;
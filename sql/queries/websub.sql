@@ -0,0 +1,41 @@
+-- name: CreateWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, callback_url, secret)
+Values (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+) ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    callback_url = EXCLUDED.callback_url,
+    secret = EXCLUDED.secret
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT *
+FROM websub_subscriptions
+WHERE feed_id = $1;
+
+-- name: ConfirmWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET lease_expires_at = $1, updated_at = $2
+WHERE feed_id = $3;
+
+-- name: GetWebSubSubscriptionsToRenew :many
+-- Subscriptions the hub never verified are requested again once they were
+-- last requested before unverified_before.
+SELECT *
+FROM websub_subscriptions
+WHERE lease_expires_at < @lease_expires_before
+OR (lease_expires_at IS NULL AND updated_at < @unverified_before)
+ORDER BY lease_expires_at ASC NULLS FIRST;
+
+-- name: DeleteWebSubSubscription :exec
+DELETE FROM websub_subscriptions
+WHERE feed_id = $1;
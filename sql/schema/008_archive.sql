@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN archive_path TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN archive_path;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE email_digests (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    email TEXT NOT NULL,
+    schedule TEXT NOT NULL,
+    format TEXT NOT NULL,
+    last_sent_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE email_digests;
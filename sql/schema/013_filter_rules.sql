@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE filter_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- rules without a follow apply to all feeds of the user
+    feed_follow_id UUID REFERENCES feed_follows(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('hide', 'only')),
+    field TEXT NOT NULL CHECK (field IN ('title', 'text')),
+    pattern TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE filter_rules;
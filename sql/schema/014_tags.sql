@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE tags (
+    feed_follow_id UUID NOT NULL REFERENCES feed_follows(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (feed_follow_id, name)
+);
+
+-- +goose Down
+DROP TABLE tags;
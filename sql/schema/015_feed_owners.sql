@@ -0,0 +1,11 @@
+-- +goose Up
+-- Feeds outlive the user who added them, they are handed over to another
+-- user before a user is deleted.
+ALTER TABLE feeds DROP CONSTRAINT feeds_user_id_fkey;
+ALTER TABLE feeds ADD CONSTRAINT feeds_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+-- +goose Down
+ALTER TABLE feeds DROP CONSTRAINT feeds_user_id_fkey;
+ALTER TABLE feeds ADD CONSTRAINT feeds_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
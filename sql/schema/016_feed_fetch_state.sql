@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN title TEXT,
+ADD COLUMN description TEXT,
+ADD COLUMN last_success_at TIMESTAMP,
+ADD COLUMN last_error TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN title,
+DROP COLUMN description,
+DROP COLUMN last_success_at,
+DROP COLUMN last_error;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN site_url TEXT,
+ADD COLUMN language TEXT,
+ADD COLUMN image_url TEXT,
+ADD COLUMN generator TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN site_url,
+DROP COLUMN language,
+DROP COLUMN image_url,
+DROP COLUMN generator;
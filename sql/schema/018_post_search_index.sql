@@ -0,0 +1,23 @@
+-- +goose Up
+-- The search vector is an index on an expression instead of a column, so
+-- selecting posts doesn't transfer it. SearchPosts uses the same expression.
+ALTER TABLE posts
+DROP COLUMN search;
+
+CREATE INDEX posts_search_idx ON posts USING GIN ((
+    setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(content, '')), 'C')
+));
+
+-- +goose Down
+DROP INDEX posts_search_idx;
+
+ALTER TABLE posts
+ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(content, '')), 'C')
+) STORED;
+
+CREATE INDEX posts_search_idx ON posts USING GIN (search);
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+)
+
+// Tags group the feeds a user follows, tags like work/security are
+// nested in the tag work.
+func handlerTag(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 3 || (cmd.args[0] != "add" && cmd.args[0] != "rm") {
+		return errors.New("usage: tag add|rm <feed> <tag>")
+	}
+	tag, err := normalizeTag(cmd.args[2])
+	if err != nil {
+		return err
+	}
+	feed, follow, err := resolveFollow(s, user, cmd.args[1])
+	if err != nil {
+		return err
+	}
+
+	if cmd.args[0] == "add" {
+		err = s.db.AddTag(context.Background(), database.AddTagParams{
+			FeedFollowID: follow.ID,
+			Name:         tag,
+			CreatedAt:    time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't tag %s: %w", feed.Name, err)
+		}
+		fmt.Printf("tagged %s with %s\n", feed.Name, tag)
+		return nil
+	}
+
+	n, err := s.db.DeleteTag(context.Background(), database.DeleteTagParams{
+		FeedFollowID: follow.ID,
+		Name:         tag,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't remove tag %s from %s: %w", tag, feed.Name, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s isn't tagged with %s", feed.Name, tag)
+	}
+	fmt.Printf("removed tag %s from %s\n", tag, feed.Name)
+	return nil
+}
+
+func handlerTags(s *state, cmd command, user database.User) error {
+	tags, err := s.db.GetTagsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get tags: %w", err)
+	}
+	if len(tags) == 0 {
+		fmt.Println("no tags")
+		return nil
+	}
+
+	// tags are ordered by name
+	for i := 0; i < len(tags); {
+		var feeds []string
+		j := i
+		for ; j < len(tags) && tags[j].Name == tags[i].Name; j++ {
+			feeds = append(feeds, tags[j].FeedName)
+		}
+		fmt.Printf("%s: %s\n", tags[i].Name, strings.Join(feeds, ", "))
+		i = j
+	}
+	return nil
+}
+
+func normalizeTag(tag string) (string, error) {
+	var parts []string
+	for _, part := range strings.Split(tag, "/") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("invalid tag %q", tag)
+	}
+	return strings.Join(parts, "/"), nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+)
+
+func seedTag(q *fakeQuerier, follow database.FeedFollow, name string) {
+	q.tags = append(q.tags, database.Tag{
+		FeedFollowID: follow.ID,
+		Name:         name,
+		CreatedAt:    time.Now().UTC(),
+	})
+}
+
+func TestHandlerTag(t *testing.T) {
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		seedFeed(q, alice, "news", "https://news.example.com/rss")
+		follow := seedFollow(q, alice, blog)
+		seedTag(q, follow, "fun")
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerTag), []handlerTest{
+		{
+			name:        "usage",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "blog"},
+			wantErr:     "usage: tag add|rm <feed> <tag>",
+		},
+		{
+			name:        "add",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "blog", " work / security/"},
+			wantOut:     []string{"tagged blog with work/security"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.tags) != 2 || q.tags[1].Name != "work/security" || q.tags[1].FeedFollowID != q.follows[0].ID {
+					t.Errorf("unexpected tags %v", q.tags)
+				}
+			},
+		},
+		{
+			name:        "add twice",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "https://example.com/rss", "fun"},
+			wantOut:     []string{"tagged blog with fun"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.tags) != 1 {
+					t.Errorf("expected one tag, got %v", q.tags)
+				}
+			},
+		},
+		{
+			name:        "feed not followed",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "news", "work"},
+			wantErr:     "alice doesn't follow news",
+		},
+		{
+			name:        "invalid tag",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"add", "blog", " / "},
+			wantErr:     "invalid tag",
+		},
+		{
+			name:        "rm",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"rm", "blog", "fun"},
+			wantOut:     []string{"removed tag fun from blog"},
+			check: func(t *testing.T, s *state, q *fakeQuerier) {
+				if len(q.tags) != 0 {
+					t.Errorf("expected no tags, got %v", q.tags)
+				}
+			},
+		},
+		{
+			name:        "rm missing tag",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"rm", "blog", "work"},
+			wantErr:     "blog isn't tagged with work",
+		},
+	})
+}
+
+func TestHandlerTags(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerTags), []handlerTest{
+		{
+			name:        "no tags",
+			currentUser: "alice",
+			setup:       func(q *fakeQuerier) { seedUser(q, "alice") },
+			wantOut:     []string{"no tags"},
+		},
+		{
+			name:        "tags with feeds",
+			currentUser: "alice",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				bob := seedUser(q, "bob")
+				blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+				news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+				seedTag(q, seedFollow(q, alice, news), "work")
+				blogFollow := seedFollow(q, alice, blog)
+				seedTag(q, blogFollow, "work")
+				seedTag(q, blogFollow, "fun")
+				seedTag(q, seedFollow(q, bob, blog), "bobs tag")
+			},
+			wantOut: []string{"fun: blog\nwork: blog, news\n"},
+			notOut:  []string{"bobs tag"},
+		},
+	})
+}
+
+func TestTagsInFollowingAndBrowse(t *testing.T) {
+	now := time.Now().UTC()
+	setup := func(q *fakeQuerier) {
+		alice := seedUser(q, "alice")
+		blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+		news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+		advisories := seedFeed(q, alice, "advisories", "https://advisories.example.com/feed")
+		seedFollow(q, alice, blog)
+		seedTag(q, seedFollow(q, alice, news), "work")
+		seedTag(q, seedFollow(q, alice, advisories), "work/security")
+		seedPost(q, blog, "blog post", "https://example.com/1", now.Add(-3*time.Hour))
+		seedPost(q, news, "news post", "https://news.example.com/1", now.Add(-2*time.Hour))
+		seedPost(q, advisories, "advisory", "https://advisories.example.com/1", now.Add(-time.Hour))
+	}
+
+	runHandlerTests(t, middlewareLoggedIn(handlerFollowing), []handlerTest{
+		{
+			name:        "following shows tags",
+			currentUser: "alice",
+			setup:       setup,
+			wantOut:     []string{"blog\n", "news (work)\n", "advisories (work/security)\n"},
+		},
+	})
+
+	runHandlerTests(t, middlewareLoggedIn(handlerBrowse), []handlerTest{
+		{
+			name:        "tag with nested tags",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"10", "--tag", "work"},
+			wantOut:     []string{"title: news post", "title: advisory"},
+			notOut:      []string{"blog post"},
+		},
+		{
+			name:        "nested tag",
+			currentUser: "alice",
+			setup:       setup,
+			args:        []string{"10", "--tag", "work/security"},
+			wantOut:     []string{"title: advisory"},
+			notOut:      []string{"blog post", "news post"},
+		},
+	})
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+)
+
+// posts loaded per feed in the reader
+const tuiPostLimit = 500
+
+type tuiPane int
+
+const (
+	feedPane tuiPane = iota
+	postPane
+	bodyPane
+)
+
+var (
+	tuiPaneStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+	tuiFocusedStyle = tuiPaneStyle.BorderForeground(lipgloss.Color("12"))
+	tuiSelectedLine = lipgloss.NewStyle().Reverse(true)
+	tuiUnreadLine   = lipgloss.NewStyle().Bold(true)
+	tuiStatusStyle  = lipgloss.NewStyle().Faint(true)
+)
+
+const tuiHelp = "tab: switch pane  j/k: move  enter: open  r: read/unread  s: star  R: refresh feed  q: quit"
+
+type feedsLoadedMsg struct {
+	feeds []database.GetUnreadCountsForUserRow
+	err   error
+}
+
+type postsLoadedMsg struct {
+	feedID uuid.UUID
+	posts  []database.GetPostForUserRow
+	err    error
+}
+
+type feedRefreshedMsg struct {
+	feedID uuid.UUID
+	name   string
+	err    error
+}
+
+// tuiModel is the state of the full-screen reader: the followed feeds,
+// the posts of the selected feed and the selected post.
+type tuiModel struct {
+	s    *state
+	user database.User
+
+	feeds []database.GetUnreadCountsForUserRow
+	posts []database.GetPostForUserRow
+	feed  int
+	post  int
+	// scroll offset of the body
+	scroll int
+	focus  tuiPane
+
+	width  int
+	height int
+	status string
+}
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+	_, err := tea.NewProgram(newTUIModel(s, user), tea.WithAltScreen()).Run()
+	return err
+}
+
+func newTUIModel(s *state, user database.User) *tuiModel {
+	return &tuiModel{
+		s:      s,
+		user:   user,
+		width:  defaultTerminalWidth,
+		height: 24,
+		status: tuiHelp,
+	}
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return m.loadFeeds()
+}
+
+func (m *tuiModel) loadFeeds() tea.Cmd {
+	return func() tea.Msg {
+		feeds, err := m.s.db.GetUnreadCountsForUser(context.Background(), m.user.ID)
+		return feedsLoadedMsg{feeds: feeds, err: err}
+	}
+}
+
+func (m *tuiModel) loadPosts(feedID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		posts, err := m.s.db.GetPostForUser(context.Background(), database.GetPostForUserParams{
+			UserID:      m.user.ID,
+			IncludeRead: true,
+			FeedID:      uuid.NullUUID{UUID: feedID, Valid: true},
+			Limit:       tuiPostLimit,
+		})
+		return postsLoadedMsg{feedID: feedID, posts: posts, err: err}
+	}
+}
+
+// refreshFeed fetches the feed like agg does.
+func (m *tuiModel) refreshFeed(feedID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		feed, err := m.s.db.GetFeedByID(context.Background(), feedID)
+		if err != nil {
+			return feedRefreshedMsg{feedID: feedID, err: err}
+		}
+		return feedRefreshedMsg{feedID: feedID, name: feed.Name, err: scrapeFeed(m.s, feed)}
+	}
+}
+
+func (m *tuiModel) selectedFeed() (database.GetUnreadCountsForUserRow, bool) {
+	if m.feed >= len(m.feeds) {
+		return database.GetUnreadCountsForUserRow{}, false
+	}
+	return m.feeds[m.feed], true
+}
+
+func (m *tuiModel) selectedPost() (*database.GetPostForUserRow, bool) {
+	if m.post >= len(m.posts) {
+		return nil, false
+	}
+	return &m.posts[m.post], true
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case feedsLoadedMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("couldn't load feeds: %v", msg.err)
+			return m, nil
+		}
+		m.feeds = msg.feeds
+		m.feed = min(m.feed, max(len(m.feeds)-1, 0))
+		if feed, ok := m.selectedFeed(); ok {
+			return m, m.loadPosts(feed.ID)
+		}
+		m.status = "you don't follow any feeds yet"
+		return m, nil
+
+	case postsLoadedMsg:
+		feed, ok := m.selectedFeed()
+		if !ok || feed.ID != msg.feedID {
+			// the selection moved on while loading
+			return m, nil
+		}
+		if msg.err != nil {
+			m.status = fmt.Sprintf("couldn't load posts: %v", msg.err)
+			return m, nil
+		}
+		m.posts = msg.posts
+		m.post = min(m.post, max(len(m.posts)-1, 0))
+		m.scroll = 0
+		return m, nil
+
+	case feedRefreshedMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("couldn't refresh %s: %v", msg.name, msg.err)
+			return m, nil
+		}
+		m.status = fmt.Sprintf("refreshed %s", msg.name)
+		return m, m.loadFeeds()
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m *tuiModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "tab", "l", "right":
+		m.focus = min(m.focus+1, bodyPane)
+	case "shift+tab", "h", "left", "esc":
+		m.focus = max(m.focus-1, feedPane)
+	case "j", "down":
+		return m, m.move(1)
+	case "k", "up":
+		return m, m.move(-1)
+	case "pgdown", " ":
+		m.scroll += m.paneHeight() / 2
+	case "pgup":
+		m.scroll = max(m.scroll-m.paneHeight()/2, 0)
+	case "enter":
+		switch m.focus {
+		case feedPane:
+			m.focus = postPane
+		case postPane:
+			m.focus = bodyPane
+			m.setRead(true)
+		}
+	case "r":
+		if post, ok := m.selectedPost(); ok {
+			m.setRead(!post.ReadAt.Valid)
+		}
+	case "s":
+		m.toggleStar()
+	case "R":
+		if feed, ok := m.selectedFeed(); ok {
+			m.status = fmt.Sprintf("refreshing %s...", feed.Name)
+			return m, m.refreshFeed(feed.ID)
+		}
+	}
+	return m, nil
+}
+
+// move moves the selection of the focused pane by delta.
+func (m *tuiModel) move(delta int) tea.Cmd {
+	switch m.focus {
+	case feedPane:
+		next := clamp(m.feed+delta, 0, len(m.feeds)-1)
+		if next == m.feed {
+			return nil
+		}
+		m.feed = next
+		m.posts = nil
+		m.post = 0
+		m.scroll = 0
+		return m.loadPosts(m.feeds[m.feed].ID)
+	case postPane:
+		m.post = clamp(m.post+delta, 0, len(m.posts)-1)
+		m.scroll = 0
+	case bodyPane:
+		m.scroll = max(m.scroll+delta, 0)
+	}
+	return nil
+}
+
+func (m *tuiModel) setRead(read bool) {
+	post, ok := m.selectedPost()
+	if !ok || post.ReadAt.Valid == read {
+		return
+	}
+	if read {
+		now := time.Now().UTC()
+		err := m.s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+			UserID: m.user.ID,
+			PostID: post.ID,
+			ReadAt: now,
+		})
+		if err != nil {
+			m.status = fmt.Sprintf("couldn't mark post as read: %v", err)
+			return
+		}
+		post.ReadAt.Time, post.ReadAt.Valid = now, true
+		m.feeds[m.feed].Unread--
+		return
+	}
+	err := m.s.db.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{
+		UserID: m.user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		m.status = fmt.Sprintf("couldn't mark post as unread: %v", err)
+		return
+	}
+	post.ReadAt.Valid = false
+	m.feeds[m.feed].Unread++
+}
+
+func (m *tuiModel) toggleStar() {
+	post, ok := m.selectedPost()
+	if !ok {
+		return
+	}
+	if post.StarredAt.Valid {
+		err := m.s.db.UnstarPost(context.Background(), database.UnstarPostParams{
+			UserID: m.user.ID,
+			PostID: post.ID,
+		})
+		if err != nil {
+			m.status = fmt.Sprintf("couldn't unstar post: %v", err)
+			return
+		}
+		post.StarredAt.Valid = false
+		return
+	}
+	now := time.Now().UTC()
+	err := m.s.db.StarPost(context.Background(), database.StarPostParams{
+		UserID:    m.user.ID,
+		PostID:    post.ID,
+		StarredAt: now,
+	})
+	if err != nil {
+		m.status = fmt.Sprintf("couldn't star post: %v", err)
+		return
+	}
+	post.StarredAt.Time, post.StarredAt.Valid = now, true
+}
+
+func (m *tuiModel) paneHeight() int {
+	// borders and the status line
+	return max(m.height-3, 1)
+}
+
+func (m *tuiModel) View() string {
+	height := m.paneHeight()
+	feedWidth := max(m.width/5, 12)
+	postWidth := max(m.width/3, 20)
+	// every pane has a border on both sides
+	bodyWidth := max(m.width-feedWidth-postWidth-6, minRenderWidth)
+
+	var feedLines []string
+	for _, feed := range m.feeds {
+		line := feed.Name
+		if feed.Unread > 0 {
+			line = fmt.Sprintf("%s (%d)", feed.Name, feed.Unread)
+		}
+		feedLines = append(feedLines, line)
+	}
+
+	var postLines []string
+	unread := map[int]bool{}
+	for i, post := range m.posts {
+		marker := "  "
+		if !post.ReadAt.Valid {
+			marker = "● "
+			unread[i] = true
+		}
+		if post.StarredAt.Valid {
+			marker = "★ "
+		}
+		title := post.Title.String
+		if title == "" {
+			title = post.Url
+		}
+		postLines = append(postLines, marker+title)
+	}
+
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.paneStyle(feedPane).Render(listPane(feedLines, m.feed, nil, feedWidth, height)),
+		m.paneStyle(postPane).Render(listPane(postLines, m.post, unread, postWidth, height)),
+		m.paneStyle(bodyPane).Render(m.bodyPane(bodyWidth, height)),
+	)
+	return panes + "\n" + tuiStatusStyle.Render(ansi.Truncate(m.status, m.width, "…"))
+}
+
+func (m *tuiModel) paneStyle(pane tuiPane) lipgloss.Style {
+	if m.focus == pane {
+		return tuiFocusedStyle
+	}
+	return tuiPaneStyle
+}
+
+// listPane renders the lines that fit into the pane, keeping the selected
+// line in view.
+func listPane(lines []string, selected int, bold map[int]bool, width, height int) string {
+	start := 0
+	if selected >= height {
+		start = selected - height + 1
+	}
+	var out []string
+	for i := start; i < len(lines) && i < start+height; i++ {
+		line := ansi.Truncate(lines[i], width, "…")
+		line += strings.Repeat(" ", max(width-ansi.StringWidth(line), 0))
+		switch {
+		case i == selected:
+			line = tuiSelectedLine.Render(line)
+		case bold[i]:
+			line = tuiUnreadLine.Render(line)
+		}
+		out = append(out, line)
+	}
+	return fillPane(out, width, height)
+}
+
+func (m *tuiModel) bodyPane(width, height int) string {
+	post, ok := m.selectedPost()
+	if !ok {
+		return fillPane(nil, width, height)
+	}
+
+	var b strings.Builder
+	b.WriteString(tuiUnreadLine.Render(post.Title.String) + "\n")
+	b.WriteString(post.FeedName)
+	if post.PublishedAt.Valid {
+		b.WriteString(" · " + post.PublishedAt.Time.Local().Format("2006-01-02 15:04"))
+	}
+	b.WriteString("\n" + post.Url + "\n\n")
+	if post.Content.Valid {
+		b.WriteString(renderHTML(post.Content.String, width))
+	} else {
+		b.WriteString(renderHTML(post.Description.String, width))
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	m.scroll = min(m.scroll, max(len(lines)-height, 0))
+	lines = lines[m.scroll:]
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, width, "")
+	}
+	return fillPane(lines[:min(len(lines), height)], width, height)
+}
+
+// fillPane pads lines to a block of width x height so the panes line up.
+func fillPane(lines []string, width, height int) string {
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+}
+
+func clamp(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// send delivers msg to the model and runs the commands it returns until
+// the model is idle.
+func send(t *testing.T, m *tuiModel, msg tea.Msg) {
+	t.Helper()
+	_, cmd := m.Update(msg)
+	for cmd != nil {
+		msg := cmd()
+		if _, ok := msg.(tea.QuitMsg); ok {
+			return
+		}
+		_, cmd = m.Update(msg)
+	}
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func newTestTUI(t *testing.T) (*tuiModel, *fakeQuerier) {
+	t.Helper()
+	s, q := newTestState(t)
+	alice := seedUser(q, "alice")
+	blog := seedFeed(q, alice, "blog", "https://example.com/rss")
+	news := seedFeed(q, alice, "news", "https://news.example.com/rss")
+	seedFollow(q, alice, blog)
+	seedFollow(q, alice, news)
+	seedPost(q, blog, "first post", "https://example.com/1", time.Now().Add(-time.Hour))
+	seedPost(q, blog, "second post", "https://example.com/2", time.Now())
+	seedPost(q, news, "headline", "https://news.example.com/1", time.Now())
+	q.posts[1].Description.String, q.posts[1].Description.Valid = "<p>hello <b>world</b></p>", true
+
+	m := newTUIModel(s, alice)
+	send(t, m, tea.WindowSizeMsg{Width: 120, Height: 20})
+	send(t, m, m.Init()())
+	return m, q
+}
+
+func TestTUILoadsFeedsAndPosts(t *testing.T) {
+	m, _ := newTestTUI(t)
+
+	view := m.View()
+	for _, want := range []string{"blog (2)", "news (1)", "second post", "first post", "hello world"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected view to contain %q, got:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "headline") {
+		t.Errorf("expected only posts of the selected feed, got:\n%s", view)
+	}
+
+	send(t, m, key("j"))
+	view = m.View()
+	if !strings.Contains(view, "headline") || strings.Contains(view, "second post") {
+		t.Errorf("expected posts of the next feed, got:\n%s", view)
+	}
+}
+
+func TestTUIMarksReadAndStars(t *testing.T) {
+	m, q := newTestTUI(t)
+
+	send(t, m, key("tab"))
+	send(t, m, key("j"))
+	send(t, m, key("enter"))
+	if len(q.reads) != 1 || q.reads[0].PostID != q.posts[0].ID {
+		t.Fatalf("expected the first post to be read, got %v", q.reads)
+	}
+	if !strings.Contains(m.View(), "blog (1)") {
+		t.Errorf("expected the unread count to drop, got:\n%s", m.View())
+	}
+
+	send(t, m, key("r"))
+	if len(q.reads) != 0 {
+		t.Errorf("expected r to mark the post unread again, got %v", q.reads)
+	}
+
+	send(t, m, key("s"))
+	if len(q.stars) != 1 || q.stars[0].PostID != q.posts[0].ID {
+		t.Fatalf("expected the post to be starred, got %v", q.stars)
+	}
+	send(t, m, key("s"))
+	if len(q.stars) != 0 {
+		t.Errorf("expected the star to be removed, got %v", q.stars)
+	}
+}
+
+func TestTUIRefreshesFeed(t *testing.T) {
+	useHostLimiter(t, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss><channel><title>blog</title>
+<item><title>fresh post</title><link>https://example.com/3</link></item>
+</channel></rss>`))
+	}))
+	defer server.Close()
+
+	m, q := newTestTUI(t)
+	q.feeds[0].Url = server.URL
+
+	send(t, m, key("R"))
+	if !strings.Contains(m.status, "refreshed blog") {
+		t.Errorf("unexpected status %q", m.status)
+	}
+	if view := m.View(); !strings.Contains(view, "blog (3)") || !strings.Contains(view, "fresh post") {
+		t.Errorf("expected the new post, got:\n%s", view)
+	}
+}
+
+func TestTUIQuits(t *testing.T) {
+	m, _ := newTestTUI(t)
+	_, cmd := m.Update(key("q"))
+	if cmd == nil {
+		t.Fatal("expected a command")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("expected q to quit")
+	}
+}
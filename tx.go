@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arglp/gator/internal/database"
+)
+
+// inTx runs fn with queries in a transaction, which is committed if fn
+// succeeds and rolled back otherwise. Without a connection, as in the tests
+// against the in-memory fake, fn runs with s.db.
+func (s *state) inTx(fn func(q database.Querier) error) error {
+	if s.conn == nil {
+		return fn(s.db)
+	}
+	tx, err := s.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("couldn't start transaction: %w", err)
+	}
+	err = fn(database.New(tx))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("couldn't commit transaction: %w", err)
+	}
+	return nil
+}
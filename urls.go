@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/arglp/gator/internal/database"
+)
+
+// trackingParams are query parameters that only tell the site where a
+// visitor came from.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+}
+
+// canonicalURL normalizes an http(s) url so that the same page gets the
+// same url: the scheme and host are lowercased, default ports, trailing
+// slashes, fragments and tracking parameters are removed and the query is
+// sorted. http and https urls stay different, see sameURLs.
+func canonicalURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid url %s", raw)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid url %s, please use an http or https url", raw)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	// escaped slashes like %2F stay escaped, RawPath is trimmed with Path
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	query := u.Query()
+	for key := range query {
+		if isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+	return u.String(), nil
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || trackingParams[key]
+}
+
+// canonicalPostURL cleans the link of a post less than canonicalURL does:
+// posts at page#entry-2 or /a/ next to /a can be different posts, so only
+// the scheme and host are lowercased and tracking parameters removed, the
+// path, the fragment and the other parameters are kept as they are. Links
+// that aren't http(s) urls are kept unchanged.
+func canonicalPostURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return raw
+	}
+	u.Host = strings.ToLower(u.Host)
+
+	if u.RawQuery != "" {
+		var kept []string
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			key, _, _ := strings.Cut(param, "=")
+			if name, err := url.QueryUnescape(key); err == nil && isTrackingParam(name) {
+				continue
+			}
+			kept = append(kept, param)
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+	return u.String()
+}
+
+// sameURLs returns the variants of a canonical url that point to the same
+// feed: the url itself and the url with the other scheme.
+func sameURLs(canonical string) []string {
+	if rest, ok := strings.CutPrefix(canonical, "https://"); ok {
+		return []string{canonical, "http://" + rest}
+	}
+	if rest, ok := strings.CutPrefix(canonical, "http://"); ok {
+		return []string{canonical, "https://" + rest}
+	}
+	return []string{canonical}
+}
+
+// getFeedByURL finds the feed with the canonical form of raw, over http or
+// https. It returns sql.ErrNoRows if there is none.
+func getFeedByURL(s *state, raw string) (database.Feed, error) {
+	canonical, err := canonicalURL(raw)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	for _, variant := range sameURLs(canonical) {
+		feed, err := s.db.GetFeed(context.Background(), variant)
+		if err == nil {
+			return feed, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return database.Feed{}, err
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "https://example.com/feed", want: "https://example.com/feed"},
+		{raw: " HTTPS://Example.COM./feed/ ", want: "https://example.com/feed"},
+		{raw: "http://example.com:80/feed", want: "http://example.com/feed"},
+		{raw: "https://example.com:443/feed", want: "https://example.com/feed"},
+		{raw: "https://example.com:8443/feed", want: "https://example.com:8443/feed"},
+		{raw: "https://example.com", want: "https://example.com/"},
+		{raw: "https://example.com/feed?utm_source=x&UTM_Medium=y&fbclid=z", want: "https://example.com/feed"},
+		{raw: "https://example.com/feed?page=2&format=rss&gclid=1#comments", want: "https://example.com/feed?format=rss&page=2"},
+		{raw: "http://[::1]:80/feed", want: "http://[::1]/feed"},
+		{raw: "http://[::1]:8080/feed", want: "http://[::1]:8080/feed"},
+		{raw: "https://user@example.com/feed", want: "https://user@example.com/feed"},
+		{raw: "https://example.com/a%2Fb/feed/", want: "https://example.com/a%2Fb/feed"},
+		{raw: "example.com/feed", wantErr: true},
+		{raw: "ftp://example.com/feed", wantErr: true},
+		{raw: "https://exa mple.com/feed", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := canonicalURL(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("expected an error for %q, got %s", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("canonicalURL(%q) = %s, %v, want %s", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestCanonicalPostURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "https://example.com/posts/1", want: "https://example.com/posts/1"},
+		{raw: " HTTPS://Example.COM/Posts/1/ ", want: "https://example.com/Posts/1/"},
+		{raw: "https://example.com/page#entry-2", want: "https://example.com/page#entry-2"},
+		{raw: "https://example.com/a%2Fb", want: "https://example.com/a%2Fb"},
+		{raw: "https://example.com/p?utm_source=rss&id=2&b=a%20b&fbclid=x", want: "https://example.com/p?id=2&b=a%20b"},
+		{raw: "https://example.com/p?utm_source=rss", want: "https://example.com/p"},
+		{raw: "/posts/3", want: "/posts/3"},
+		{raw: "mailto:someone@example.com", want: "mailto:someone@example.com"},
+	}
+	for _, tt := range tests {
+		if got := canonicalPostURL(tt.raw); got != tt.want {
+			t.Errorf("canonicalPostURL(%q) = %s, want %s", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestStorePostsCanonicalURLs(t *testing.T) {
+	s, q := newTestState(t)
+	alice := seedUser(q, "alice")
+	feed := seedFeed(q, alice, "blog", "https://example.com/rss")
+	seedPost(q, feed, "first", "https://example.com/posts/1", time.Now())
+
+	rssFeed, err := parseFeed([]byte(`<rss><channel>
+<item><title>first again</title><link>https://Example.com/posts/1?utm_source=rss</link></item>
+<item><title>index</title><link>https://example.com/posts/1/</link></item>
+<item><title>entry 2</title><link>https://example.com/posts/2#entry-2</link></item>
+<item><title>entry 3</title><link>https://example.com/posts/2#entry-3</link></item>
+<item><title>relative</title><link>/posts/3</link></item>
+</channel></rss>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storePosts(s, feed, rssFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var urls []string
+	for _, post := range q.posts {
+		urls = append(urls, post.Url)
+	}
+	want := []string{
+		"https://example.com/posts/1",
+		"https://example.com/posts/1/",
+		"https://example.com/posts/2#entry-2",
+		"https://example.com/posts/2#entry-3",
+		"/posts/3",
+	}
+	if !slices.Equal(urls, want) {
+		t.Errorf("expected posts %v, got %v", want, urls)
+	}
+}
+
+func TestHandlerFollowCanonicalURL(t *testing.T) {
+	runHandlerTests(t, middlewareLoggedIn(handlerFollow), []handlerTest{
+		{
+			name:        "other form of the url",
+			currentUser: "bob",
+			setup: func(q *fakeQuerier) {
+				alice := seedUser(q, "alice")
+				seedUser(q, "bob")
+				seedFeed(q, alice, "blog", "https://example.com/rss")
+			},
+			args:    []string{"http://EXAMPLE.com/rss/?utm_campaign=x"},
+			wantOut: []string{"user: bob, feed: blog"},
+		},
+	})
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// lease we ask the hubs for, they are free to grant a different one
+	websubLeaseSeconds = 10 * 24 * 60 * 60
+	// subscriptions are renewed once their lease expires within this window
+	websubRenewBefore = time.Hour
+	// subscriptions the hub hasn't verified within this time, because it
+	// or the callback was unreachable, are requested again
+	websubVerifyTimeout = 15 * time.Minute
+	websubRenewInterval = 5 * time.Minute
+	websubMaxBodySize   = 10 << 20
+)
+
+// websubSubscriber subscribes to WebSub hubs and receives the content
+// they distribute on callbackURL/websub/<feed id>.
+type websubSubscriber struct {
+	s           *state
+	callbackURL string
+	client      *http.Client
+}
+
+func newWebSubSubscriber(s *state, callbackURL string) *websubSubscriber {
+	return &websubSubscriber{
+		s:           s,
+		callbackURL: strings.TrimSuffix(callbackURL, "/"),
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func handlerServeWebSub(s *state, cmd command) error {
+	if len(cmd.args) < 2 {
+		return errors.New("please provide a listen address and the public callback url")
+	}
+
+	ws := newWebSubSubscriber(s, cmd.args[1])
+
+	listener, err := net.Listen("tcp", cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	// hubs may verify the intent before they answer the subscription
+	// request, so the callback has to be reachable before subscribing
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- http.Serve(listener, ws.handler())
+	}()
+	fmt.Printf("receiving websub callbacks on %s\n", listener.Addr())
+
+	err = ws.subscribeAll(context.Background())
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(websubRenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-serveErr:
+			return err
+		case <-ticker.C:
+			ws.refresh(context.Background())
+		}
+	}
+}
+
+// refresh renews the subscriptions that need it and subscribes to the
+// hubs agg found since the last refresh.
+func (ws *websubSubscriber) refresh(ctx context.Context) {
+	ws.renew(ctx)
+	err := ws.subscribeAll(ctx)
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+// subscribeAll subscribes to the hub of every feed that advertised one,
+// skipping feeds whose subscription is still good or was just requested,
+// the hub has websubVerifyTimeout to verify it and renew retries it after.
+func (ws *websubSubscriber) subscribeAll(ctx context.Context) error {
+	feeds, err := ws.s.db.GetFeedsWithHub(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get feeds with hubs: %w", err)
+	}
+
+	for _, feed := range feeds {
+		secret := ""
+		sub, err := ws.s.db.GetWebSubSubscription(ctx, feed.ID)
+		if err == nil && sub.HubUrl == feed.HubUrl.String && sub.TopicUrl == feed.TopicUrl.String {
+			if sub.LeaseExpiresAt.Valid && sub.LeaseExpiresAt.Time.After(time.Now().UTC().Add(websubRenewBefore)) {
+				continue
+			}
+			if time.Since(sub.UpdatedAt) < websubVerifyTimeout {
+				continue
+			}
+			secret = sub.Secret
+		}
+
+		err = ws.subscribe(ctx, feed.ID, feed.HubUrl.String, feed.TopicUrl.String, secret)
+		if err != nil {
+			log.Printf("couldn't subscribe to %s: %v", feed.TopicUrl.String, err)
+			continue
+		}
+		log.Printf("requested subscription to %s at %s", feed.TopicUrl.String, feed.HubUrl.String)
+	}
+	return nil
+}
+
+// renew resubscribes every subscription whose lease is about to expire or
+// that the hub didn't verify in time.
+func (ws *websubSubscriber) renew(ctx context.Context) {
+	subs, err := ws.s.db.GetWebSubSubscriptionsToRenew(ctx, database.GetWebSubSubscriptionsToRenewParams{
+		LeaseExpiresBefore: sql.NullTime{
+			Time:  time.Now().UTC().Add(websubRenewBefore),
+			Valid: true,
+		},
+		UnverifiedBefore: time.Now().UTC().Add(-websubVerifyTimeout),
+	})
+	if err != nil {
+		log.Printf("couldn't get subscriptions to renew: %v", err)
+		return
+	}
+
+	for _, sub := range subs {
+		err = ws.subscribe(ctx, sub.FeedID, sub.HubUrl, sub.TopicUrl, sub.Secret)
+		if err != nil {
+			log.Printf("couldn't renew subscription to %s: %v", sub.TopicUrl, err)
+			continue
+		}
+		log.Printf("requested renewal of %s", sub.TopicUrl)
+	}
+}
+
+// subscribe stores the subscription and sends the subscription request to
+// the hub. An empty secret generates a new one.
+func (ws *websubSubscriber) subscribe(ctx context.Context, feedID uuid.UUID, hubURL, topicURL, secret string) error {
+	if secret == "" {
+		buf := make([]byte, 32)
+		_, err := rand.Read(buf)
+		if err != nil {
+			return err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+	callback := ws.callbackURL + "/websub/" + feedID.String()
+
+	_, err := ws.s.db.CreateWebSubSubscription(ctx, database.CreateWebSubSubscriptionParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+		FeedID:      feedID,
+		HubUrl:      hubURL,
+		TopicUrl:    topicURL,
+		CallbackUrl: callback,
+		Secret:      secret,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't store subscription: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("hub.callback", callback)
+	form.Set("hub.mode", "subscribe")
+	form.Set("hub.topic", topicURL)
+	form.Set("hub.secret", secret)
+	form.Set("hub.lease_seconds", strconv.Itoa(websubLeaseSeconds))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", hubURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "gator")
+
+	res, err := ws.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("hub refused subscription: %s", res.Status)
+	}
+	return nil
+}
+
+func (ws *websubSubscriber) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /websub/{feedID}", ws.handleVerification)
+	mux.HandleFunc("POST /websub/{feedID}", ws.handleContent)
+	return mux
+}
+
+func (ws *websubSubscriber) subscription(r *http.Request) (database.WebsubSubscription, bool) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		return database.WebsubSubscription{}, false
+	}
+	sub, err := ws.s.db.GetWebSubSubscription(r.Context(), feedID)
+	if err != nil {
+		return database.WebsubSubscription{}, false
+	}
+	return sub, true
+}
+
+// handleVerification answers the hub's intent verification for
+// subscriptions we asked for and records the lease it granted. Only a
+// subscription requested within websubVerifyTimeout is confirmed, gator
+// never unsubscribes, so unsubscribe requests aren't ours and are refused.
+func (ws *websubSubscriber) handleVerification(w http.ResponseWriter, r *http.Request) {
+	sub, ok := ws.subscription(r)
+	query := r.URL.Query()
+	if !ok || query.Get("hub.topic") != sub.TopicUrl {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		if time.Since(sub.UpdatedAt) > websubVerifyTimeout {
+			http.NotFound(w, r)
+			return
+		}
+		leaseSeconds, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || leaseSeconds <= 0 {
+			http.Error(w, "invalid hub.lease_seconds", http.StatusBadRequest)
+			return
+		}
+		// a longer lease than we asked for is renewed as if it were the
+		// one we asked for, huge ones would overflow the duration
+		leaseSeconds = min(leaseSeconds, websubLeaseSeconds)
+		err = ws.s.db.ConfirmWebSubSubscription(r.Context(), database.ConfirmWebSubSubscriptionParams{
+			LeaseExpiresAt: sql.NullTime{
+				Time:  time.Now().UTC().Add(time.Duration(leaseSeconds) * time.Second),
+				Valid: true,
+			},
+			UpdatedAt: time.Now().UTC(),
+			FeedID:    sub.FeedID,
+		})
+		if err != nil {
+			http.Error(w, "couldn't confirm subscription", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("subscribed to %s for %ds", sub.TopicUrl, leaseSeconds)
+	case "denied":
+		err := ws.s.db.DeleteWebSubSubscription(r.Context(), sub.FeedID)
+		if err != nil {
+			http.Error(w, "couldn't delete subscription", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("hub denied subscription to %s: %s", sub.TopicUrl, query.Get("hub.reason"))
+		w.WriteHeader(http.StatusOK)
+		return
+	case "unsubscribe":
+		http.NotFound(w, r)
+		return
+	default:
+		http.Error(w, "invalid hub.mode", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(query.Get("hub.challenge")))
+}
+
+// handleContent stores the posts of a content distribution request. Per
+// spec requests with a wrong signature are acknowledged but dropped.
+func (ws *websubSubscriber) handleContent(w http.ResponseWriter, r *http.Request) {
+	sub, ok := ws.subscription(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, websubMaxBodySize))
+	if err != nil {
+		http.Error(w, "couldn't read body", http.StatusBadRequest)
+		return
+	}
+
+	if !validSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		log.Printf("dropping content for %s with invalid signature", sub.TopicUrl)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	rssFeed, err := parseFeed(body)
+	if err != nil {
+		log.Printf("dropping content for %s: %v", sub.TopicUrl, err)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	feed, err := ws.s.db.GetFeedByID(r.Context(), sub.FeedID)
+	if err != nil {
+		http.Error(w, "couldn't find feed", http.StatusInternalServerError)
+		return
+	}
+
+	err = storePosts(ws.s, feed, rssFeed)
+	if err != nil {
+		http.Error(w, "couldn't store posts", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("received %d items for %s", len(rssFeed.Channel.Item), sub.TopicUrl)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature checks an X-Hub-Signature header of the form
+// method=hexdigest against the HMAC of body.
+func validSignature(secret, signature string, body []byte) bool {
+	method, digest, ok := strings.Cut(signature, "=")
+	if !ok {
+		return false
+	}
+
+	var h func() hash.Hash
+	switch method {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arglp/gator/internal/database"
+)
+
+const websubTestFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Example</title>
+  <atom:link rel="hub" href="https://hub.example.com/" />
+  <link>https://example.com/</link>
+  <atom:link rel="self" href="https://example.com/rss" type="application/rss+xml" />
+  <description>An example feed</description>
+  <item>
+    <title>Pushed post</title>
+    <link>https://example.com/pushed</link>
+    <description>Delivered by the hub</description>
+    <pubDate>Mon, 02 Jan 2006 15:04:05 MST</pubDate>
+  </item>
+</channel>
+</rss>`
+
+func TestRSSFeedHubLinks(t *testing.T) {
+	feed, err := parseFeed([]byte(websubTestFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Channel.Link != "https://example.com/" {
+		t.Errorf("expected channel link to survive atom links, got %q", feed.Channel.Link)
+	}
+	hub, topic := feed.hubLinks()
+	if hub != "https://hub.example.com/" || topic != "https://example.com/rss" {
+		t.Errorf("unexpected hub links %q %q", hub, topic)
+	}
+
+	feed, err = parseFeed([]byte(`<rss><channel><link>https://example.com/</link></channel></rss>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub, topic = feed.hubLinks()
+	if hub != "" || topic != "" {
+		t.Errorf("expected no hub links, got %q %q", hub, topic)
+	}
+}
+
+func TestValidSignature(t *testing.T) {
+	body := []byte("payload")
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		want      bool
+	}{
+		{name: "valid", secret: "secret", signature: signature, want: true},
+		{name: "wrong secret", secret: "other", signature: signature, want: false},
+		{name: "missing", secret: "secret", signature: "", want: false},
+		{name: "unknown method", secret: "secret", signature: "md5=abc", want: false},
+		{name: "not hex", secret: "secret", signature: "sha256=zz", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validSignature(tt.secret, tt.signature, body); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// standInHub records the subscription requests it receives, the test then
+// plays the hub's part of verifying and distributing content.
+type standInHub struct {
+	*httptest.Server
+	requests chan url.Values
+}
+
+func newStandInHub(t *testing.T) *standInHub {
+	hub := &standInHub{requests: make(chan url.Values, 10)}
+	hub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("hub couldn't parse subscription request: %v", err)
+		}
+		hub.requests <- r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(hub.Close)
+	return hub
+}
+
+func (hub *standInHub) nextRequest(t *testing.T) url.Values {
+	t.Helper()
+	select {
+	case form := <-hub.requests:
+		return form
+	case <-time.After(5 * time.Second):
+		t.Fatal("hub didn't receive a subscription request")
+		return nil
+	}
+}
+
+func TestWebSubSubscription(t *testing.T) {
+	s, q := newTestState(t)
+	alice := seedUser(q, "alice")
+	feed := seedFeed(q, alice, "example", "https://example.com/rss")
+	hub := newStandInHub(t)
+	q.feeds[0].HubUrl = sql.NullString{String: hub.URL, Valid: true}
+	q.feeds[0].TopicUrl = sql.NullString{String: "https://example.com/rss", Valid: true}
+
+	ws := newWebSubSubscriber(s, "")
+	callback := httptest.NewServer(ws.handler())
+	defer callback.Close()
+	ws.callbackURL = callback.URL
+
+	err := ws.subscribeAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := hub.nextRequest(t)
+	if form.Get("hub.mode") != "subscribe" || form.Get("hub.topic") != "https://example.com/rss" {
+		t.Fatalf("unexpected subscription request %v", form)
+	}
+	if form.Get("hub.callback") != callback.URL+"/websub/"+feed.ID.String() {
+		t.Fatalf("unexpected callback %q", form.Get("hub.callback"))
+	}
+	secret := form.Get("hub.secret")
+	if secret == "" {
+		t.Fatal("expected a secret")
+	}
+
+	verify := func(topic, mode, challenge string) (int, string) {
+		t.Helper()
+		query := url.Values{}
+		query.Set("hub.mode", mode)
+		query.Set("hub.topic", topic)
+		query.Set("hub.challenge", challenge)
+		query.Set("hub.lease_seconds", "7200")
+		res, err := http.Get(form.Get("hub.callback") + "?" + query.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+
+	status, _ := verify("https://other.example.com/rss", "subscribe", "nope")
+	if status != http.StatusNotFound {
+		t.Errorf("expected verification of another topic to be refused, got %d", status)
+	}
+
+	status, body := verify("https://example.com/rss", "subscribe", "challenge-123")
+	if status != http.StatusOK || body != "challenge-123" {
+		t.Fatalf("expected challenge to be echoed, got %d %q", status, body)
+	}
+	if !q.websubs[0].LeaseExpiresAt.Valid || time.Until(q.websubs[0].LeaseExpiresAt.Time) < time.Hour {
+		t.Errorf("expected lease to be recorded, got %v", q.websubs[0].LeaseExpiresAt)
+	}
+
+	distribute := func(signature string) int {
+		t.Helper()
+		req, err := http.NewRequest("POST", form.Get("hub.callback"), strings.NewReader(websubTestFeed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/rss+xml")
+		req.Header.Set("X-Hub-Signature", signature)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	status = distribute("sha256=" + strings.Repeat("0", 64))
+	if status/100 != 2 {
+		t.Errorf("expected badly signed content to be acknowledged, got %d", status)
+	}
+	if len(q.posts) != 0 {
+		t.Fatalf("expected badly signed content to be dropped, got %d posts", len(q.posts))
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(websubTestFeed))
+	status = distribute("sha256=" + hex.EncodeToString(mac.Sum(nil)))
+	if status/100 != 2 {
+		t.Errorf("expected content to be accepted, got %d", status)
+	}
+	if len(q.posts) != 1 || q.posts[0].Url != "https://example.com/pushed" || q.posts[0].FeedID != feed.ID {
+		t.Fatalf("expected pushed post to be stored, got %v", q.posts)
+	}
+
+	// a live subscription isn't requested again
+	err = ws.subscribeAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case form := <-hub.requests:
+		t.Fatalf("unexpected subscription request %v", form)
+	default:
+	}
+
+	q.websubs[0].LeaseExpiresAt.Time = time.Now().UTC().Add(10 * time.Minute)
+	ws.renew(context.Background())
+	renewal := hub.nextRequest(t)
+	if renewal.Get("hub.topic") != "https://example.com/rss" || renewal.Get("hub.secret") != secret {
+		t.Errorf("expected renewal with the same secret, got %v", renewal)
+	}
+
+	// gator never unsubscribes
+	status, body = verify("https://example.com/rss", "unsubscribe", "bye")
+	if status != http.StatusNotFound || body == "bye" {
+		t.Errorf("expected unsubscribe to be refused, got %d %q", status, body)
+	}
+	if len(q.websubs) != 1 {
+		t.Errorf("expected subscription to be kept, got %v", q.websubs)
+	}
+}
+
+func TestWebSubVerification(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		lease      string
+		requested  time.Duration
+		wantStatus int
+		wantLease  time.Duration
+		wantGone   bool
+	}{
+		{name: "pending subscription", mode: "subscribe", lease: "7200", wantStatus: http.StatusOK, wantLease: 2 * time.Hour},
+		{name: "no pending request", mode: "subscribe", lease: "7200", requested: websubVerifyTimeout + time.Minute, wantStatus: http.StatusNotFound},
+		{name: "zero lease", mode: "subscribe", lease: "0", wantStatus: http.StatusBadRequest},
+		{name: "negative lease", mode: "subscribe", lease: "-60", wantStatus: http.StatusBadRequest},
+		{name: "huge lease", mode: "subscribe", lease: "9223372036854775807", wantStatus: http.StatusOK, wantLease: websubLeaseSeconds * time.Second},
+		{name: "unsubscribe", mode: "unsubscribe", wantStatus: http.StatusNotFound},
+		{name: "denied", mode: "denied", wantStatus: http.StatusOK, wantGone: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, q := newTestState(t)
+			feed := seedFeed(q, seedUser(q, "alice"), "example", "https://example.com/rss")
+			q.websubs = []database.WebsubSubscription{{
+				FeedID:    feed.ID,
+				UpdatedAt: time.Now().UTC().Add(-tt.requested),
+				TopicUrl:  "https://example.com/rss",
+			}}
+
+			query := url.Values{}
+			query.Set("hub.mode", tt.mode)
+			query.Set("hub.topic", "https://example.com/rss")
+			query.Set("hub.challenge", "challenge-123")
+			query.Set("hub.lease_seconds", tt.lease)
+			rec := httptest.NewRecorder()
+			newWebSubSubscriber(s, "").handler().ServeHTTP(rec, httptest.NewRequest("GET", "/websub/"+feed.ID.String()+"?"+query.Encode(), nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantGone {
+				if len(q.websubs) != 0 {
+					t.Errorf("expected subscription to be removed, got %v", q.websubs)
+				}
+				return
+			}
+			lease := q.websubs[0].LeaseExpiresAt
+			if tt.wantLease == 0 {
+				if lease.Valid || rec.Body.String() == "challenge-123" {
+					t.Errorf("expected the subscription to stay unconfirmed, got %v %q", lease, rec.Body.String())
+				}
+				return
+			}
+			if rec.Body.String() != "challenge-123" {
+				t.Errorf("expected challenge to be echoed, got %q", rec.Body.String())
+			}
+			if !lease.Valid || time.Until(lease.Time) > tt.wantLease || time.Until(lease.Time) < tt.wantLease-time.Minute {
+				t.Errorf("expected a lease of %v, got %v", tt.wantLease, lease)
+			}
+		})
+	}
+}
+
+func TestWebSubRetriesUnverifiedSubscription(t *testing.T) {
+	s, q := newTestState(t)
+	seedFeed(q, seedUser(q, "alice"), "example", "https://example.com/rss")
+	hub := newStandInHub(t)
+	q.feeds[0].HubUrl = sql.NullString{String: hub.URL, Valid: true}
+	q.feeds[0].TopicUrl = sql.NullString{String: "https://example.com/rss", Valid: true}
+
+	// the callback is never reached, so the hub can't verify the subscription
+	ws := newWebSubSubscriber(s, "http://127.0.0.1:1")
+	err := ws.subscribeAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := hub.nextRequest(t).Get("hub.secret")
+
+	ws.renew(context.Background())
+	select {
+	case form := <-hub.requests:
+		t.Fatalf("expected the hub to get time to verify, got %v", form)
+	default:
+	}
+
+	q.websubs[0].UpdatedAt = time.Now().UTC().Add(-websubVerifyTimeout - time.Minute)
+	ws.renew(context.Background())
+	retry := hub.nextRequest(t)
+	if retry.Get("hub.topic") != "https://example.com/rss" || retry.Get("hub.secret") != secret {
+		t.Errorf("expected the subscription to be requested again with the same secret, got %v", retry)
+	}
+	if time.Since(q.websubs[0].UpdatedAt) > time.Minute {
+		t.Errorf("expected the retry to be recorded, got %v", q.websubs[0].UpdatedAt)
+	}
+}
+
+func TestWebSubRefreshSubscribesNewHubs(t *testing.T) {
+	s, q := newTestState(t)
+	alice := seedUser(q, "alice")
+	seedFeed(q, alice, "example", "https://example.com/rss")
+	seedFeed(q, alice, "news", "https://news.example.com/rss")
+	hub := newStandInHub(t)
+	q.feeds[0].HubUrl = sql.NullString{String: hub.URL, Valid: true}
+	q.feeds[0].TopicUrl = sql.NullString{String: "https://example.com/rss", Valid: true}
+
+	ws := newWebSubSubscriber(s, "http://127.0.0.1:1")
+	err := ws.subscribeAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.nextRequest(t)
+
+	// agg finds the hub of news, example is still waiting for verification
+	q.feeds[1].HubUrl = sql.NullString{String: hub.URL, Valid: true}
+	q.feeds[1].TopicUrl = sql.NullString{String: "https://news.example.com/rss", Valid: true}
+	ws.refresh(context.Background())
+	if form := hub.nextRequest(t); form.Get("hub.topic") != "https://news.example.com/rss" {
+		t.Errorf("expected a subscription to news, got %v", form)
+	}
+	select {
+	case form := <-hub.requests:
+		t.Fatalf("unexpected subscription request %v", form)
+	default:
+	}
+}